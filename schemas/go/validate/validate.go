@@ -0,0 +1,181 @@
+// Package validate checks event payloads against the JSON Schemas in
+// schemas/event, resolving their $refs into schemas/common.
+//
+// The schema files are embedded from ./schemas, which `make gen` copies from
+// the repository's schemas directory alongside the generated events package.
+package validate
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+//go:embed schemas
+var files embed.FS
+
+// baseURL is the fictitious location the embedded schemas are registered
+// under, so relative $refs such as ../common/model.v1.json resolve between
+// them. Nothing is fetched from it.
+const baseURL = "https://schemas.llm-your-business.internal/"
+
+// eventFile matches event schema file names: <event>.v<version>.json, where
+// <event> is also the event's Kafka topic.
+var eventFile = regexp.MustCompile(`^(.+)\.v([0-9]+)\.json$`)
+
+type key struct {
+	event   string
+	version int
+}
+
+// Validator holds the compiled event schemas. It is safe for concurrent use.
+type Validator struct {
+	schemas map[key]*jsonschema.Schema
+}
+
+// New compiles every embedded event schema. Formats such as uuid are
+// annotations only, as the 2020-12 draft specifies by default.
+func New() (*Validator, error) {
+	c := jsonschema.NewCompiler()
+	var events []string
+	err := fs.WalkDir(files, "schemas", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(p) != ".json" {
+			return err
+		}
+		raw, err := files.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		rel := strings.TrimPrefix(p, "schemas/")
+		if err := c.AddResource(baseURL+rel, doc); err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		if path.Dir(rel) == "event" {
+			events = append(events, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("load schemas: %w", err)
+	}
+
+	v := &Validator{schemas: make(map[key]*jsonschema.Schema, len(events))}
+	for _, rel := range events {
+		m := eventFile.FindStringSubmatch(path.Base(rel))
+		if m == nil {
+			return nil, fmt.Errorf("schema %s: name is not <event>.v<version>.json", rel)
+		}
+		version, _ := strconv.Atoi(m[2])
+		sch, err := c.Compile(baseURL + rel)
+		if err != nil {
+			return nil, fmt.Errorf("compile %s: %w", rel, err)
+		}
+		v.schemas[key{m[1], version}] = sch
+	}
+	return v, nil
+}
+
+// Has reports whether there is a schema for event at any version.
+func (v *Validator) Has(event string) bool {
+	for k := range v.schemas {
+		if k.event == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Versions returns the schema versions known for event, in ascending order.
+func (v *Validator) Versions(event string) []int {
+	var out []int
+	for k := range v.schemas {
+		if k.event == event {
+			out = append(out, k.version)
+		}
+	}
+	sort.Ints(out)
+	return out
+}
+
+// Validate checks payload against the schema for event at the payload's
+// meta.schema_version (1 when absent). Payloads that are not valid JSON,
+// carry an unknown schema_version or violate the schema yield an *Error.
+func (v *Validator) Validate(event string, payload []byte) error {
+	if !v.Has(event) {
+		return fmt.Errorf("validate: no schema for event %q", event)
+	}
+	inst, err := jsonschema.UnmarshalJSON(bytes.NewReader(payload))
+	if err != nil {
+		return &Error{Event: event, Problems: []string{"invalid JSON: " + err.Error()}}
+	}
+	version := SchemaVersion(inst)
+	sch, ok := v.schemas[key{event, version}]
+	if !ok {
+		return &Error{Event: event, Version: version, Problems: []string{fmt.Sprintf("unsupported schema_version %d", version)}}
+	}
+	if err := sch.Validate(inst); err != nil {
+		ve, ok := err.(*jsonschema.ValidationError)
+		if !ok {
+			return &Error{Event: event, Version: version, Problems: []string{err.Error()}}
+		}
+		return &Error{Event: event, Version: version, Problems: leaves(ve, nil)}
+	}
+	return nil
+}
+
+// SchemaVersion returns meta.schema_version of a decoded payload, or 1 when it
+// is absent or not an integer; validation then reports the bad value.
+func SchemaVersion(inst any) int {
+	obj, _ := inst.(map[string]any)
+	meta, _ := obj["meta"].(map[string]any)
+	switch n := meta["schema_version"].(type) {
+	case json.Number:
+		if i, err := n.Int64(); err == nil && i > 0 {
+			return int(i)
+		}
+	case float64:
+		if n > 0 && n == float64(int(n)) {
+			return int(n)
+		}
+	}
+	return 1
+}
+
+// leaves flattens a validation error tree into its innermost failures, each
+// prefixed with the offending instance location.
+func leaves(e *jsonschema.ValidationError, out []string) []string {
+	if len(e.Causes) == 0 {
+		return append(out, e.Error())
+	}
+	for _, c := range e.Causes {
+		out = leaves(c, out)
+	}
+	return out
+}
+
+// Error describes why a payload does not match its event schema.
+type Error struct {
+	Event    string
+	Version  int      // schema_version the payload was checked against; 0 if unknown
+	Problems []string // one entry per failed constraint, e.g. "at '/meta': missing property 'producer'"
+}
+
+func (e *Error) Error() string {
+	if e.Version == 0 {
+		return fmt.Sprintf("invalid %s event: %s", e.Event, strings.Join(e.Problems, "; "))
+	}
+	return fmt.Sprintf("invalid %s v%d event: %s", e.Event, e.Version, strings.Join(e.Problems, "; "))
+}
@@ -9,6 +9,8 @@ const (
 	RunScheduleDaily   RunSchedule = "daily"
 	RunScheduleWeekly  RunSchedule = "weekly"
 	RunScheduleMonthly RunSchedule = "monthly"
+	// RunScheduleCron runs on the objective's ScheduleCron expression.
+	RunScheduleCron RunSchedule = "cron"
 )
 
 // Language enumerates supported languages.
@@ -16,8 +18,29 @@ type Language string
 
 // ObjectiveV1JsonRunsElem is a single run entry for an objective.
 type ObjectiveV1JsonRunsElem struct {
-	Timestamp  time.Time `json:"timestamp"`
-	ManifestId string    `json:"manifest_id"`
+	Timestamp    time.Time `json:"timestamp"`
+	ManifestId   string    `json:"manifest_id"`
+	ScheduledFor time.Time `json:"scheduled_for"`      // schedule slot the run covers; zero for runs recorded before slots were tracked
+	Backfill     bool      `json:"backfill,omitempty"` // run caught up a slot missed while the scheduler was down
+	Manual       bool      `json:"manual,omitempty"`   // triggered through the admin API, outside the schedule
+	Skipped      bool      `json:"skipped,omitempty"`  // slot not executed because a budget was exhausted; no manifest
+	SkipReason   string    `json:"skip_reason,omitempty"`
+}
+
+// Budget caps spend over UTC calendar days and months. Zero fields are not
+// enforced.
+type Budget struct {
+	DailyTokens    int64   `json:"daily_tokens,omitempty"` // input plus output tokens
+	MonthlyTokens  int64   `json:"monthly_tokens,omitempty"`
+	DailyCostUSD   float64 `json:"daily_cost_usd,omitempty"`
+	MonthlyCostUSD float64 `json:"monthly_cost_usd,omitempty"`
+}
+
+// BudgetHold records why scheduled runs of an objective are being deferred.
+// It is cleared by the next run that goes ahead.
+type BudgetHold struct {
+	Reason string    `json:"reason"`
+	Since  time.Time `json:"since"`
 }
 
 // ObjectiveTargets is a single targets object whose fields are slices.
@@ -35,9 +58,15 @@ type ObjectiveV1Json struct {
 	ObjectiveType string                    `json:"objective_type"`
 	Targets       ObjectiveTargets          `json:"targets"`
 	PartnerId     string                    `json:"partner_id"`
+	Budget        *Budget                   `json:"budget,omitempty"`      // per-objective spend caps; partner caps live in partner_budgets
+	BudgetHold    *BudgetHold               `json:"budget_hold,omitempty"` // set by the scheduler while runs are deferred over budget
 	ProductId     string                    `json:"product_id"`
 	IsActive      bool                      `json:"is_active"`
+	Paused        bool                      `json:"paused,omitempty"`     // set by the scheduler admin API; no scheduled runs while true
+	ResumedAt     *time.Time                `json:"resumed_at,omitempty"` // slots before this were paused, not missed
 	RunSchedule   RunSchedule               `json:"run_schedule"`
+	ScheduleCron  string                    `json:"schedule_cron,omitempty"` // 5-field cron, used when RunSchedule is "cron"
+	TimeZone      string                    `json:"time_zone,omitempty"`     // IANA zone for the schedule, e.g. "Europe/Berlin"; empty means UTC
 	StartDate     time.Time                 `json:"start_date"`
 	Runs          []ObjectiveV1JsonRunsElem `json:"runs"`
 	CreatedAt     time.Time                 `json:"created_at"`
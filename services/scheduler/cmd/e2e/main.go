@@ -0,0 +1,37 @@
+// Command e2e drives one scheduled objective run end to end on the in-process
+// broker and store, and exits non-zero if it does not complete.
+//
+//	go run ./cmd/e2e [-timeout 30s] [-v]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"llm-your-business/services/scheduler/internal/e2e"
+	"llm-your-business/services/scheduler/internal/logging"
+)
+
+func main() {
+	timeout := flag.Duration("timeout", 30*time.Second, "how long the run may take")
+	verbose := flag.Bool("v", false, "log at debug level")
+	flag.Parse()
+
+	level := slog.LevelWarn
+	if *verbose {
+		level = slog.LevelDebug
+	}
+	logging.Setup(level)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	report, err := e2e.Run(ctx)
+	if err != nil {
+		logging.Fatal("e2e run failed", "err", err)
+	}
+	fmt.Printf("ok: manifest %s %s: %d questions, %d answers, %d datapoints, $%.6f spent\n",
+		report.ManifestID, report.Execution.Status, report.Questions, report.Answers, report.Datapoints, report.SpendUSD)
+}
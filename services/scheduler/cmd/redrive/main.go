@@ -0,0 +1,48 @@
+// Command redrive moves dead-lettered messages back onto their source topic.
+//
+//	go run ./cmd/redrive -topic objective.execution.answer [-limit 100]
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os/signal"
+	"syscall"
+
+	"llm-your-business/services/scheduler/internal/config"
+	"llm-your-business/services/scheduler/internal/kafka"
+	"llm-your-business/services/scheduler/internal/logging"
+)
+
+func main() {
+	topic := flag.String("topic", "", "source topic whose dead-letter topic should be re-driven (required)")
+	limit := flag.Int("limit", 0, "maximum number of messages to move (0 = all)")
+	flag.Parse()
+	if *topic == "" {
+		flag.Usage()
+		logging.Fatal("-topic is required")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	cfg, err := config.Load()
+	if err != nil {
+		logging.Fatal("config error", "err", err)
+	}
+	logging.Setup(cfg.LogLevel)
+	producer, err := kafka.NewProducer(kafka.NewBroker(cfg))
+	if err != nil {
+		logging.Fatal("kafka producer init error", "err", err)
+	}
+	defer func() {
+		_ = producer.Close(context.Background())
+	}()
+
+	n, err := kafka.Redrive(ctx, cfg, producer, *topic, *limit)
+	if err != nil {
+		logging.Fatal("redrive error", "topic", *topic, "moved", n, "err", err)
+	}
+	slog.Info("redrive complete", "topic", *topic, "moved", n)
+}
@@ -2,17 +2,31 @@ package main
 
 import (
 	"context"
-	"log"
+	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
+	_ "time/tzdata" // objectives name IANA zones; do not depend on the image having zoneinfo
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"llm-your-business/services/scheduler/internal/admin"
+	"llm-your-business/services/scheduler/internal/budget"
 	"llm-your-business/services/scheduler/internal/config"
 	"llm-your-business/services/scheduler/internal/db"
+	"llm-your-business/services/scheduler/internal/db/memory"
+	"llm-your-business/services/scheduler/internal/extract"
 	"llm-your-business/services/scheduler/internal/handlers"
+	"llm-your-business/services/scheduler/internal/health"
 	"llm-your-business/services/scheduler/internal/kafka"
+	"llm-your-business/services/scheduler/internal/leader"
+	"llm-your-business/services/scheduler/internal/logging"
+	"llm-your-business/services/scheduler/internal/registry"
 	schedpkg "llm-your-business/services/scheduler/internal/scheduler"
+	"llm-your-business/services/scheduler/internal/tracing"
 )
 
 func main() {
@@ -22,71 +36,212 @@ func main() {
 
 	cfg, err := config.Load()
 	if err != nil {
-		log.Fatalf("config error: %v", err)
+		logging.Fatal("config error", "err", err)
+	}
+	logging.Setup(cfg.LogLevel)
+
+	shutdownTracing, err := tracing.Setup(ctx, "scheduler", cfg.TracesExporter)
+	if err != nil {
+		logging.Fatal("tracing init error", "err", err)
 	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(shutdownCtx); err != nil {
+			slog.Error("tracing shutdown error", "err", err)
+		}
+	}()
 
-	// MongoDB (optional, disabled by default)
-	var (
-		h           *handlers.Handlers
-		mongoClient *db.Client
-	)
+	// MongoDB (optional, disabled by default). Without it the scheduler keeps
+	// its state in memory, optionally seeded from MEMORY_SEED.
+	var store db.Store
+	var mongoClient *db.Client
 	if cfg.DBEnabled {
 		mc, err := db.NewClient(ctx, cfg.MongoURI, cfg.MongoDatabase)
 		if err != nil {
-			log.Fatalf("mongodb connect error: %v", err)
+			logging.Fatal("mongodb connect error", "err", err)
 		}
-		defer func() {
-			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-			defer cancel()
-			if err := mc.Disconnect(shutdownCtx); err != nil {
-				log.Printf("mongodb disconnect error: %v", err)
+		if err := mc.EnsureProcessedEventsTTL(ctx, cfg.DedupeTTL); err != nil {
+			logging.Fatal("mongodb index error", "err", err)
+		}
+		mongoClient = mc
+		store = mc
+	} else {
+		mem := memory.New()
+		if cfg.MemorySeed != "" {
+			if err := seedMemory(mem, cfg.MemorySeed); err != nil {
+				logging.Fatal("memory seed error", "err", err)
+			}
+		}
+		slog.Warn("DB disabled; scheduler state is kept in memory and lost on exit")
+		store = mem
+	}
+	// Kafka producer (available for handlers or future publishing)
+	broker := kafka.NewBroker(cfg)
+	producer, err := kafka.NewProducer(broker)
+	if err != nil {
+		logging.Fatal("kafka producer init error", "err", err)
+	}
+
+	// Leader election keeps replicas from executing the same objective twice.
+	// The lease is held until shutdown has drained a running tick, so no other
+	// replica starts ticking before this one has stopped.
+	var elector *leader.Elector
+	leaderCtx, stopLeader := context.WithCancel(context.WithoutCancel(ctx))
+	leaderDone := make(chan struct{})
+	if mongoClient != nil && cfg.LeaderElection {
+		elector = leader.New(mongoClient, "scheduler", cfg.LeaderLeaseTTL)
+		go func() {
+			defer close(leaderDone)
+			if err := elector.Run(leaderCtx); err != nil && err != context.Canceled {
+				slog.Error("leader election stopped with error", "err", err)
 			}
 		}()
-		mongoClient = mc
-		// DB is connected for readiness, but handlers do not persist yet.
-		h = handlers.New()
 	} else {
-		h = handlers.New()
+		close(leaderDone)
 	}
 
-	// Kafka producer (available for handlers or future publishing)
-	producer, err := kafka.NewProducer(cfg)
+	// Spend budgets are recorded and checked in the store.
+	budgets, err := budget.New(store, cfg)
 	if err != nil {
-		log.Fatalf("kafka producer init error: %v", err)
+		logging.Fatal("budget init error", "err", err)
 	}
-	defer func() {
-		_ = producer.Close(context.Background())
-	}()
 
-    // Scheduler service packs common deps for future scheduling logic
-    schedulerSvc := schedpkg.New(producer, mongoClient, cfg)
-    go func() {
-        if err := schedulerSvc.Start(ctx); err != nil && err != context.Canceled {
-            log.Printf("scheduler service stopped with error: %v", err)
-            cancel()
-        }
-    }()
+	// Scheduler service packs common deps for future scheduling logic
+	schedulerSvc := schedpkg.New(producer, store, elector, budgets, cfg)
+	go func() {
+		if err := schedulerSvc.Start(ctx); err != nil && err != context.Canceled {
+			slog.Error("scheduler service stopped with error", "err", err)
+			cancel()
+		}
+	}()
+	go func() {
+		if err := schedulerSvc.RunOutboxRelay(ctx); err != nil && err != context.Canceled {
+			slog.Error("outbox relay stopped with error", "err", err)
+			cancel()
+		}
+	}()
+	go func() {
+		if err := schedulerSvc.RunWatchdog(ctx); err != nil && err != context.Canceled {
+			slog.Error("question watchdog stopped with error", "err", err)
+			cancel()
+		}
+	}()
 
-	consumer, err := kafka.NewConsumer(cfg, h)
+	reg := registry.New()
+	handlers.New(store, extract.New(producer), cfg).Register(reg)
+	budgets.Register(reg)
+	consumer, err := kafka.NewConsumer(cfg, broker, reg, producer)
 	if err != nil {
-		log.Fatalf("kafka consumer init error: %v", err)
+		logging.Fatal("kafka consumer init error", "err", err)
+	}
+
+	// Health probes and the admin API.
+	probes := health.New()
+	probes.Live("scheduler", func(context.Context) error { return schedulerSvc.CheckTicking(time.Now()) })
+	if mongoClient != nil {
+		probes.Ready("mongodb", mongoClient.Ping)
 	}
+	probes.Ready("kafka_producer", producer.Ping)
+	probes.Ready("kafka_consumer", consumer.Ping)
+	mux := http.NewServeMux()
+	probes.Register(mux)
+	mux.Handle("GET /metrics", promhttp.Handler())
+	switch {
+	case cfg.AdminToken != "":
+		mux.Handle("/admin/", admin.New(admin.Options{Scheduler: schedulerSvc, DB: store, Token: cfg.AdminToken}).Router())
+	case cfg.AdminInsecure:
+		mux.Handle("/admin/", admin.New(admin.Options{Scheduler: schedulerSvc, DB: store, Insecure: true}).Router())
+		slog.Warn("admin API has no ADMIN_TOKEN and ADMIN_INSECURE is set; requests are not authenticated")
+	default:
+		slog.Warn("admin API disabled: set ADMIN_TOKEN (or ADMIN_INSECURE=true for local development)")
+	}
+	httpSrv := &http.Server{
+		Addr:              ":" + cfg.HTTPPort,
+		Handler:           mux,
+		ReadTimeout:       15 * time.Second,
+		ReadHeaderTimeout: 10 * time.Second,
+		WriteTimeout:      60 * time.Second,
+		IdleTimeout:       120 * time.Second,
+	}
+	go func() {
+		slog.Info("scheduler HTTP listening", "port", cfg.HTTPPort)
+		if err := httpSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			slog.Error("http server stopped with error", "err", err)
+			cancel()
+		}
+	}()
 
 	// Start consuming in background
 	go func() {
-		if err := consumer.Start(ctx); err != nil {
-			log.Printf("kafka consumer stopped with error: %v", err)
+		if err := consumer.Start(ctx); err != nil && err != context.Canceled {
+			slog.Error("kafka consumer stopped with error", "err", err)
 			cancel()
 		}
 	}()
 
-	// Block until signal
+	// Block until signal. Canceling ctx stops fetching, ticking, relaying and
+	// the watchdog; work already under way carries on below.
 	<-ctx.Done()
+	slog.Info("shutting down", "timeout", cfg.ShutdownTimeout.String())
+	shutdown(cfg.ShutdownTimeout, consumer, schedulerSvc, producer, httpSrv)
+	stopLeader()
+	<-leaderDone
+	if mongoClient != nil {
+		// Closed last: handlers and the tick drained above write to it.
+		disconnectCtx, cancelDisconnect := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := mongoClient.Disconnect(disconnectCtx); err != nil {
+			slog.Error("mongodb disconnect error", "err", err)
+		}
+		cancelDisconnect()
+	}
+	_ = os.Stdout.Sync()
+}
+
+// shutdown drains in-flight work within timeout: it waits for the consumer's
+// handlers and the scheduler's running tick, relay or watchdog pass, and for
+// admin requests, then flushes the producer and leaves the consumer groups.
+// Work still running at the deadline is canceled and its messages stay
+// uncommitted, to be redelivered.
+func shutdown(timeout time.Duration, consumer *kafka.Consumer, svc *schedpkg.Service, producer *kafka.Producer, httpSrv *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
 
-	// Best-effort close (Start observes ctx cancellation)
-	_ = consumer.Close(context.Background())
+	var wg sync.WaitGroup
+	drain := func(name string, fn func(context.Context) error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := fn(ctx); err != nil {
+				slog.Error("shutdown: "+name+" not drained", "err", err)
+			}
+		}()
+	}
+	drain("kafka consumer", consumer.Drain)
+	drain("scheduler", svc.Drain)
+	drain("http server", httpSrv.Shutdown)
+	wg.Wait()
 
-	// small delay to flush logs when running via Docker
-	_ = os.Stderr.Sync()
-	time.Sleep(100 * time.Millisecond)
+	// Handlers publish synchronously, so every message they produced is
+	// written once they are drained; Close flushes whatever writers hold.
+	if err := producer.Close(ctx); err != nil {
+		slog.Error("shutdown: kafka producer close error", "err", err)
+	}
+	// Offsets of handled messages were committed as each finished. Leaving
+	// the groups now hands their partitions over without waiting for a
+	// session timeout.
+	if err := consumer.Close(ctx); err != nil {
+		slog.Error("shutdown: kafka consumer close error", "err", err)
+	}
+	slog.Info("shutdown complete")
+}
+
+// seedMemory loads the JSON seed file at path into mem.
+func seedMemory(mem *memory.Store, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return mem.Seed(f)
 }
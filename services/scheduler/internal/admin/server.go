@@ -0,0 +1,186 @@
+// Package admin serves the scheduler's operator HTTP API: trigger, pause and
+// resume objectives and inspect their runs and next run time.
+package admin
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	model "llm-your-business/services/go/models"
+	"llm-your-business/services/scheduler/internal/budget"
+	"llm-your-business/services/scheduler/internal/db"
+	"llm-your-business/services/scheduler/internal/logging"
+	"llm-your-business/services/scheduler/internal/scheduler"
+)
+
+type Options struct {
+	Scheduler *scheduler.Service
+	DB        db.Store
+	Token     string // bearer token required on every request
+	Insecure  bool   // with no Token, serve requests unauthenticated instead of refusing them
+}
+
+type Server struct {
+	svc      *scheduler.Service
+	db       db.Store
+	token    string
+	insecure bool
+}
+
+func New(opts Options) *Server {
+	return &Server{svc: opts.Scheduler, db: opts.DB, token: opts.Token, insecure: opts.Insecure}
+}
+
+func (s *Server) Router() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /admin/objectives/{id}/trigger", s.postTrigger)
+	mux.HandleFunc("POST /admin/objectives/{id}/pause", s.postPause)
+	mux.HandleFunc("POST /admin/objectives/{id}/resume", s.postResume)
+	mux.HandleFunc("GET /admin/objectives/{id}/runs", s.getRuns)
+	mux.HandleFunc("GET /admin/objectives/{id}/next-run", s.getNextRun)
+
+	return requestLog(s.auth(mux))
+}
+
+// postTrigger runs the objective now, regardless of its schedule. Budgets
+// still apply: an exhausted one is reported as a conflict.
+func (s *Server) postTrigger(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	manifestID, err := s.svc.TriggerObjective(r.Context(), id)
+	if errors.Is(err, db.ErrNotFound) {
+		http.Error(w, "objective not found", http.StatusNotFound)
+		return
+	}
+	var exceeded *budget.Exceeded
+	if errors.As(err, &exceeded) {
+		http.Error(w, exceeded.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "admin: trigger error", "objective_id", id, "err", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if manifestID == "" {
+		http.Error(w, "objective has no questions", http.StatusConflict)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]any{"objective_id": id, "manifest_id": manifestID})
+}
+
+func (s *Server) postPause(w http.ResponseWriter, r *http.Request)  { s.setPaused(w, r, true) }
+func (s *Server) postResume(w http.ResponseWriter, r *http.Request) { s.setPaused(w, r, false) }
+
+func (s *Server) setPaused(w http.ResponseWriter, r *http.Request, paused bool) {
+	id := r.PathValue("id")
+	err := s.db.SetObjectivePaused(r.Context(), id, paused)
+	if errors.Is(err, db.ErrNotFound) {
+		http.Error(w, "objective not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "admin: pause error", "objective_id", id, "paused", paused, "err", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"objective_id": id, "paused": paused})
+}
+
+// getRuns lists the objective's recorded runs, newest first.
+func (s *Server) getRuns(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	obj, ok := s.findObjective(w, r, id)
+	if !ok {
+		return
+	}
+	runs := obj.Runs
+	for i, j := 0, len(runs)-1; i < j; i, j = i+1, j-1 {
+		runs[i], runs[j] = runs[j], runs[i]
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"objective_id": id, "runs": runs})
+}
+
+// getNextRun reports the next schedule slot after now.
+func (s *Server) getNextRun(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	obj, ok := s.findObjective(w, r, id)
+	if !ok {
+		return
+	}
+	next, err := scheduler.NextRun(obj, time.Now())
+	if err != nil {
+		http.Error(w, "invalid schedule: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	resp := map[string]any{"objective_id": id, "paused": obj.Paused, "next_run_at": nil}
+	if !next.IsZero() {
+		resp["next_run_at"] = next.UTC()
+		resp["next_run_local"] = next.Format(time.RFC3339)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) findObjective(w http.ResponseWriter, r *http.Request, id string) (obj model.ObjectiveV1Json, ok bool) {
+	obj, err := s.db.FindObjective(r.Context(), id)
+	if errors.Is(err, db.ErrNotFound) {
+		http.Error(w, "objective not found", http.StatusNotFound)
+		return obj, false
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "admin: find objective error", "objective_id", id, "err", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return obj, false
+	}
+	return obj, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// auth requires "Authorization: Bearer <token>". Without a token every
+// request is refused, unless the server was explicitly made insecure.
+func (s *Server) auth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" || !s.insecure {
+			got := r.Header.Get("Authorization")
+			want := "Bearer " + s.token
+			if s.token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(want)) != 1 {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestLog tags each request with a request_id, taken from X-Request-Id
+// or generated, and logs it once handled.
+func requestLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id := r.Header.Get("X-Request-Id")
+		if id == "" {
+			id = newRequestID()
+		}
+		w.Header().Set("X-Request-Id", id)
+		r = r.WithContext(logging.With(r.Context(), "request_id", id))
+		next.ServeHTTP(w, r)
+		slog.InfoContext(r.Context(), "admin request", "method", r.Method, "path", r.URL.Path, "duration", time.Since(start).String())
+	})
+}
+
+func newRequestID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
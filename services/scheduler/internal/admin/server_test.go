@@ -0,0 +1,79 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	model "llm-your-business/services/go/models"
+	"llm-your-business/services/scheduler/internal/db"
+)
+
+func TestAuth(t *testing.T) {
+	tests := []struct {
+		name     string
+		token    string
+		insecure bool
+		header   string
+		want     int
+	}{
+		{name: "valid token", token: "s3cret", header: "Bearer s3cret", want: http.StatusOK},
+		{name: "wrong token", token: "s3cret", header: "Bearer s3cre", want: http.StatusUnauthorized},
+		{name: "missing header", token: "s3cret", want: http.StatusUnauthorized},
+		{name: "not a bearer token", token: "s3cret", header: "s3cret", want: http.StatusUnauthorized},
+		{name: "insecure does not bypass a token", token: "s3cret", insecure: true, want: http.StatusUnauthorized},
+		{name: "no token refuses everything", want: http.StatusUnauthorized},
+		{name: "no token refuses an empty bearer", header: "Bearer ", want: http.StatusUnauthorized},
+		{name: "insecure without token", insecure: true, want: http.StatusOK},
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(Options{Token: tt.token, Insecure: tt.insecure})
+			req := httptest.NewRequest(http.MethodGet, "/admin/objectives/o1/runs", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			s.auth(ok).ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+// failingStore fails the lookups the admin API makes with a driver-like error.
+type failingStore struct{ db.Store }
+
+var errDriver = errors.New("connection(mongodb:27017[-3]) socket was unexpectedly closed")
+
+func (failingStore) FindObjective(context.Context, string) (model.ObjectiveV1Json, error) {
+	return model.ObjectiveV1Json{}, errDriver
+}
+
+func (failingStore) SetObjectivePaused(context.Context, string, bool) error { return errDriver }
+
+func TestInternalErrorsHidden(t *testing.T) {
+	s := New(Options{DB: failingStore{}, Insecure: true})
+	for _, req := range []struct{ method, path string }{
+		{http.MethodPost, "/admin/objectives/o1/pause"},
+		{http.MethodPost, "/admin/objectives/o1/resume"},
+		{http.MethodGet, "/admin/objectives/o1/runs"},
+		{http.MethodGet, "/admin/objectives/o1/next-run"},
+	} {
+		t.Run(req.method+" "+req.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.Router().ServeHTTP(rec, httptest.NewRequest(req.method, req.path, nil))
+			if rec.Code != http.StatusInternalServerError {
+				t.Fatalf("status = %d, want 500", rec.Code)
+			}
+			if body := strings.TrimSpace(rec.Body.String()); body != http.StatusText(http.StatusInternalServerError) {
+				t.Fatalf("body = %q, want the generic status text", body)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+// Package budget accumulates the spend of answers per objective and partner
+// and checks it against their daily and monthly budgets.
+//
+// Objective budgets are the objective's budget field; partner budgets are
+// documents in partner_budgets keyed by partner_id. Days and months are UTC
+// calendar periods.
+package budget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"llm-your-business/schemas/events"
+	model "llm-your-business/services/go/models"
+	"llm-your-business/services/scheduler/internal/config"
+	"llm-your-business/services/scheduler/internal/db"
+	"llm-your-business/services/scheduler/internal/decode"
+	"llm-your-business/services/scheduler/internal/metrics"
+	"llm-your-business/services/scheduler/internal/registry"
+)
+
+// Budget scopes and periods, as reported in Exceeded.
+const (
+	ScopeObjective = "objective"
+	ScopePartner   = "partner"
+	PeriodDaily    = "daily"
+	PeriodMonthly  = "monthly"
+)
+
+// Tracker records spend from answer events and checks budgets.
+type Tracker struct {
+	db      db.Store
+	pricing Pricing
+}
+
+func New(store db.Store, cfg *config.Config) (*Tracker, error) {
+	pricing, err := ParsePricing(cfg.ModelPricing)
+	if err != nil {
+		return nil, err
+	}
+	return &Tracker{db: store, pricing: pricing}, nil
+}
+
+// Register subscribes the tracker to answer events.
+func (t *Tracker) Register(r *registry.Registry) {
+	registry.Subscribe(r, decode.Answer, "record-spend", t.HandleObjectiveExecutionAnswer)
+}
+
+// HandleObjectiveExecutionAnswer prices the answer's tokens and records the
+// spend against its objective and the objective's partner. Each answer
+// attempt is charged once; cache hits are recorded at no cost.
+func (t *Tracker) HandleObjectiveExecutionAnswer(ctx context.Context, e events.ObjectiveExecutionAnswerV1Json) error {
+	if t.db == nil {
+		return nil
+	}
+	var partnerID string
+	obj, err := t.db.FindObjective(ctx, e.Meta.ObjectiveId)
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		slog.WarnContext(ctx, "budget: answer for unknown objective; charged to the objective only")
+	case err != nil:
+		return fmt.Errorf("find objective: %w", err)
+	default:
+		partnerID = obj.PartnerId
+	}
+
+	at := time.UnixMilli(int64(e.Meta.CreatedAt)).UTC()
+	if e.Meta.CreatedAt <= 0 {
+		at = time.Now().UTC()
+	}
+	rec := db.SpendRecord{
+		ID:          db.SpendKey(e.Meta.ExecutionId, e.Meta.QuestionId, e.Meta.RunAttempt),
+		ObjectiveId: e.Meta.ObjectiveId,
+		PartnerId:   partnerID,
+		Model:       string(e.Meta.Model),
+		At:          at,
+	}
+	if md := e.Data.ExecutionMetadata; !md.CacheHit {
+		rec.InputTokens = int64(md.InputTokens)
+		rec.OutputTokens = int64(md.OutputTokens)
+		rec.CostUSD = t.pricing.Cost(e.Meta.Model, rec.InputTokens, rec.OutputTokens)
+	}
+	inserted, err := t.db.RecordSpend(ctx, rec)
+	if err != nil {
+		return fmt.Errorf("record spend: %w", err)
+	}
+	if inserted {
+		metrics.SpendUSD.WithLabelValues(rec.Model).Add(rec.CostUSD)
+		metrics.SpendTokens.WithLabelValues(rec.Model, "input").Add(float64(rec.InputTokens))
+		metrics.SpendTokens.WithLabelValues(rec.Model, "output").Add(float64(rec.OutputTokens))
+	}
+	return nil
+}
+
+// Check returns the first exhausted budget of the objective or its partner,
+// or nil when the objective may run. The objective's own budget is checked
+// first.
+func (t *Tracker) Check(ctx context.Context, objectiveID string, obj model.ObjectiveV1Json, now time.Time) (*Exceeded, error) {
+	now = now.UTC()
+	if obj.Budget != nil {
+		ex, err := check(ctx, ScopeObjective, obj.Budget, now, func(ctx context.Context, since time.Time) (db.Spend, error) {
+			return t.db.ObjectiveSpendSince(ctx, objectiveID, since)
+		})
+		if ex != nil || err != nil {
+			return ex, err
+		}
+	}
+	if obj.PartnerId == "" {
+		return nil, nil
+	}
+	pb, err := t.db.FindPartnerBudget(ctx, obj.PartnerId)
+	if err != nil {
+		return nil, fmt.Errorf("find partner budget: %w", err)
+	}
+	if pb == nil {
+		return nil, nil
+	}
+	return check(ctx, ScopePartner, pb, now, func(ctx context.Context, since time.Time) (db.Spend, error) {
+		return t.db.PartnerSpendSince(ctx, obj.PartnerId, since)
+	})
+}
+
+// check compares b with the spend of the current day and month, querying only
+// the periods b limits.
+func check(ctx context.Context, scope string, b *model.Budget, now time.Time, spendSince func(context.Context, time.Time) (db.Spend, error)) (*Exceeded, error) {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	periods := []struct {
+		name       string
+		start, end time.Time
+		tokens     int64
+		costUSD    float64
+	}{
+		{PeriodDaily, day, day.AddDate(0, 0, 1), b.DailyTokens, b.DailyCostUSD},
+		{PeriodMonthly, month, month.AddDate(0, 1, 0), b.MonthlyTokens, b.MonthlyCostUSD},
+	}
+	for _, p := range periods {
+		if p.tokens <= 0 && p.costUSD <= 0 {
+			continue
+		}
+		spent, err := spendSince(ctx, p.start)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s spend: %w", scope, p.name, err)
+		}
+		ex := &Exceeded{Scope: scope, Period: p.name, Until: p.end}
+		switch {
+		case p.tokens > 0 && spent.Tokens >= p.tokens:
+			ex.Limit, ex.Max, ex.Spent = "tokens", float64(p.tokens), float64(spent.Tokens)
+		case p.costUSD > 0 && spent.CostUSD >= p.costUSD:
+			ex.Limit, ex.Max, ex.Spent = "cost_usd", p.costUSD, spent.CostUSD
+		default:
+			continue
+		}
+		return ex, nil
+	}
+	return nil, nil
+}
+
+// Exceeded describes an exhausted budget.
+type Exceeded struct {
+	Scope  string    // ScopeObjective or ScopePartner
+	Period string    // PeriodDaily or PeriodMonthly
+	Limit  string    // "tokens" or "cost_usd"
+	Max    float64   // the budget
+	Spent  float64   // spend in the current period
+	Until  time.Time // when the period ends
+}
+
+// Reason describes the exhausted budget without the spend, so it stays the
+// same while the period lasts.
+func (e *Exceeded) Reason() string {
+	return fmt.Sprintf("%s %s %s budget of %g exhausted until %s", e.Scope, e.Period, e.Limit, e.Max, e.Until.Format(time.RFC3339))
+}
+
+func (e *Exceeded) Error() string {
+	return fmt.Sprintf("%s %s %s budget exhausted: spent %g of %g", e.Scope, e.Period, e.Limit, e.Spent, e.Max)
+}
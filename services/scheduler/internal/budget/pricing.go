@@ -0,0 +1,61 @@
+package budget
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"llm-your-business/schemas/events"
+)
+
+// Price is what a model charges, in USD per million tokens.
+type Price struct {
+	InputPerMTok  float64 `json:"input_per_mtok"`
+	OutputPerMTok float64 `json:"output_per_mtok"`
+}
+
+// Pricing maps each model to its price.
+type Pricing map[events.Model]Price
+
+// DefaultPricing holds list prices at the time of writing. Override them with
+// MODEL_PRICING rather than editing them for a single deployment.
+var DefaultPricing = Pricing{
+	events.ModelCHATGPT5: {InputPerMTok: 1.25, OutputPerMTok: 10},
+	events.ModelCLAUDE35: {InputPerMTok: 3, OutputPerMTok: 15},
+	events.ModelGEMINI2:  {InputPerMTok: 0.10, OutputPerMTok: 0.40},
+	events.ModelLLAMA4:   {InputPerMTok: 0.27, OutputPerMTok: 0.85},
+}
+
+// ParsePricing returns DefaultPricing with the models in overrides replaced.
+// overrides is a JSON object keyed by model, e.g.
+// {"CHAT_GPT5": {"input_per_mtok": 1.25, "output_per_mtok": 10}}; empty keeps
+// the defaults.
+func ParsePricing(overrides string) (Pricing, error) {
+	p := make(Pricing, len(DefaultPricing))
+	for m, price := range DefaultPricing {
+		p[m] = price
+	}
+	if overrides == "" {
+		return p, nil
+	}
+	var o map[events.Model]Price
+	if err := json.Unmarshal([]byte(overrides), &o); err != nil {
+		return nil, fmt.Errorf("MODEL_PRICING: %w", err)
+	}
+	for m, price := range o {
+		if _, ok := DefaultPricing[m]; !ok {
+			return nil, fmt.Errorf("MODEL_PRICING: unknown model %q", m)
+		}
+		if price.InputPerMTok < 0 || price.OutputPerMTok < 0 {
+			return nil, fmt.Errorf("MODEL_PRICING: negative price for %s", m)
+		}
+		p[m] = price
+	}
+	return p, nil
+}
+
+// Cost returns the USD cost of a call to model. Models without a price cost
+// nothing.
+func (p Pricing) Cost(model events.Model, inputTokens, outputTokens int64) float64 {
+	price := p[model]
+	return (float64(inputTokens)*price.InputPerMTok + float64(outputTokens)*price.OutputPerMTok) / 1e6
+}
@@ -2,25 +2,100 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"log/slog"
 	"os"
+	"strconv"
 	"strings"
+	"time"
+
+	"llm-your-business/services/scheduler/internal/logging"
+)
+
+// Catch-up policies for schedule slots missed while the scheduler was down.
+const (
+	CatchUpSkip   = "skip"   // only run today's slot, as if nothing was missed
+	CatchUpLatest = "latest" // run the most recent missed slot once
+	CatchUpAll    = "all"    // run every missed slot, oldest first
+)
+
+// What the scheduler does with a run whose objective or partner budget is
+// exhausted, selected with BUDGET_EXHAUSTED_ACTION.
+const (
+	BudgetDefer = "defer" // leave the slot pending and try again on later ticks
+	BudgetSkip  = "skip"  // record the slot as skipped and move on
+)
+
+// Trace exporters selectable with OTEL_TRACES_EXPORTER.
+const (
+	TracesNone   = "none"   // spans are not recorded; trace context is still propagated
+	TracesOTLP   = "otlp"   // OTLP over HTTP, configured by the OTEL_EXPORTER_OTLP_* variables
+	TracesStdout = "stdout" // one JSON span per line on stdout, for local use
 )
 
 type Config struct {
 	// General
 	AppEnv   string
-	LogLevel string
+	LogLevel slog.Level
 
 	// Kafka
 	KafkaBrokers  []string
 	KafkaGroupID  string
 	KafkaClientID string
-	KafkaTopics   []string
+	KafkaTopics   []string // narrows the registered topics; empty consumes all
+	KafkaWorkers  int      // handler goroutines per consumed topic
 
 	// MongoDB
 	DBEnabled     bool
 	MongoURI      string
 	MongoDatabase string
+	MemorySeed    string // JSON file loaded into the in-memory store when the DB is disabled
+
+	// Executions
+	ExecutionTimeout time.Duration // how long a manifest may wait for all answers
+	MaxFanout        int           // cap on questions x models x locations per run; 0 disables
+
+	// Catch-up of missed schedule slots
+	CatchUpPolicy  string // one of CatchUpSkip, CatchUpLatest, CatchUpAll
+	CatchUpMaxRuns int    // most backfilled runs per objective per tick
+
+	// Spend budgets
+	BudgetAction string // one of BudgetDefer, BudgetSkip
+	ModelPricing string // JSON overrides of the per-model token prices; see package budget
+
+	// Watchdog re-emitting unanswered questions
+	QuestionTimeout     time.Duration // wait this long for an answer before re-emitting
+	QuestionMaxAttempts int           // run_attempt after which a question is failed
+	WatchdogInterval    time.Duration
+
+	// Retry / dead-letter handling of failed dispatches
+	RetryMaxAttempts    int
+	RetryInitialBackoff time.Duration
+	RetryMaxBackoff     time.Duration
+
+	// Dedupe records for handled events expire after this long
+	DedupeTTL time.Duration
+
+	// How often the outbox relay looks for unpublished messages, and how many
+	// times it tries to publish one before setting it aside as failed
+	OutboxPollInterval time.Duration
+	OutboxMaxAttempts  int
+
+	// Tracing
+	TracesExporter string // one of TracesNone, TracesOTLP, TracesStdout
+
+	// HTTP server for health probes and the admin API (admin served with
+	// ADMIN_TOKEN or ADMIN_INSECURE, on either store)
+	HTTPPort      string
+	AdminToken    string // bearer token for the admin API; without it the API is not served
+	AdminInsecure bool   // serve the admin API without ADMIN_TOKEN (local development only)
+
+	// Leader election between scheduler replicas (requires DB)
+	LeaderElection bool
+	LeaderLeaseTTL time.Duration
+
+	// How long shutdown waits for in-flight handlers and a running tick
+	ShutdownTimeout time.Duration
 }
 
 func getenv(key, def string) string {
@@ -45,6 +120,36 @@ func splitCSV(s string) []string {
 	return out
 }
 
+func parseDuration(key string, def time.Duration) (time.Duration, error) {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", key, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%s must be positive", key)
+	}
+	return d, nil
+}
+
+func parseInt(key string, def int) (int, error) {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", key, err)
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("%s must not be negative", key)
+	}
+	return n, nil
+}
+
 func parseBool(s string) (bool, bool) {
 	switch strings.ToLower(strings.TrimSpace(s)) {
 	case "1", "true", "yes", "on":
@@ -60,19 +165,29 @@ func parseBool(s string) (bool, bool) {
 
 // Load reads configuration from environment variables.
 // Required vars: KAFKA_BOOTSTRAP_SERVERS, KAFKA_CONSUMER_GROUP, MONGODB_URI, MONGODB_DATABASE
-// Optional: KAFKA_TOPICS (CSV), KAFKA_CLIENT_ID, APP_ENV, LOG_LEVEL
+// Optional: KAFKA_TOPICS (CSV), KAFKA_CLIENT_ID, APP_ENV, LOG_LEVEL, EXECUTION_TIMEOUT,
+// RETRY_MAX_ATTEMPTS, RETRY_INITIAL_BACKOFF, RETRY_MAX_BACKOFF, DEDUPE_TTL,
+// OUTBOX_POLL_INTERVAL, OUTBOX_MAX_ATTEMPTS, LEADER_ELECTION, LEADER_LEASE_TTL, FANOUT_MAX_QUESTIONS,
+// QUESTION_TIMEOUT, QUESTION_MAX_ATTEMPTS, WATCHDOG_INTERVAL, CATCHUP_POLICY,
+// CATCHUP_MAX_RUNS, HTTP_PORT, ADMIN_TOKEN, ADMIN_INSECURE, OTEL_TRACES_EXPORTER,
+// BUDGET_EXHAUSTED_ACTION, MODEL_PRICING
 func Load() (*Config, error) {
 	cfg := &Config{
-		AppEnv:   getenv("APP_ENV", "development"),
-		LogLevel: getenv("LOG_LEVEL", "info"),
+		AppEnv: getenv("APP_ENV", "development"),
 
 		KafkaBrokers:  splitCSV(getenv("KAFKA_BOOTSTRAP_SERVERS", "")),
 		KafkaGroupID:  getenv("KAFKA_CONSUMER_GROUP", getenv("KAFKA_GROUP_ID", "")),
 		KafkaClientID: getenv("KAFKA_CLIENT_ID", "scheduler"),
-		KafkaTopics:   splitCSV(getenv("KAFKA_TOPICS", "objective.execution.question,objective.execution.answer,objective.datapoint,objective.manifest")),
+		KafkaTopics:   splitCSV(getenv("KAFKA_TOPICS", "")),
 
 		MongoURI:      getenv("MONGODB_URI", getenv("MONGO_URI", "")),
 		MongoDatabase: getenv("MONGODB_DATABASE", getenv("MONGO_DATABASE", "")),
+		MemorySeed:    os.Getenv("MEMORY_SEED"),
+
+		HTTPPort:   getenv("HTTP_PORT", getenv("ADMIN_PORT", "8086")),
+		AdminToken: os.Getenv("ADMIN_TOKEN"),
+
+		ModelPricing: os.Getenv("MODEL_PRICING"),
 	}
 
 	// DB enabled flag (optional). Accept either DB_ENABLED or MONGODB_ENABLED.
@@ -84,6 +199,105 @@ func Load() (*Config, error) {
 		cfg.DBEnabled = false
 	}
 
+	cfg.AdminInsecure, _ = parseBool(os.Getenv("ADMIN_INSECURE"))
+
+	level, err := logging.ParseLevel(getenv("LOG_LEVEL", "info"))
+	if err != nil {
+		return nil, fmt.Errorf("LOG_LEVEL: %w", err)
+	}
+	cfg.LogLevel = level
+
+	timeout, err := parseDuration("EXECUTION_TIMEOUT", 2*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ExecutionTimeout = timeout
+	if cfg.MaxFanout, err = parseInt("FANOUT_MAX_QUESTIONS", 1000); err != nil {
+		return nil, err
+	}
+
+	cfg.CatchUpPolicy = strings.ToLower(getenv("CATCHUP_POLICY", CatchUpLatest))
+	switch cfg.CatchUpPolicy {
+	case CatchUpSkip, CatchUpLatest, CatchUpAll:
+	default:
+		return nil, fmt.Errorf("CATCHUP_POLICY must be %s, %s or %s", CatchUpSkip, CatchUpLatest, CatchUpAll)
+	}
+	if cfg.CatchUpMaxRuns, err = parseInt("CATCHUP_MAX_RUNS", 10); err != nil {
+		return nil, err
+	}
+	if cfg.CatchUpMaxRuns < 1 {
+		return nil, errors.New("CATCHUP_MAX_RUNS must be at least 1")
+	}
+
+	cfg.BudgetAction = strings.ToLower(getenv("BUDGET_EXHAUSTED_ACTION", BudgetDefer))
+	switch cfg.BudgetAction {
+	case BudgetDefer, BudgetSkip:
+	default:
+		return nil, fmt.Errorf("BUDGET_EXHAUSTED_ACTION must be %s or %s", BudgetDefer, BudgetSkip)
+	}
+
+	cfg.TracesExporter = strings.ToLower(getenv("OTEL_TRACES_EXPORTER", TracesNone))
+	switch cfg.TracesExporter {
+	case TracesNone, TracesOTLP, TracesStdout:
+	default:
+		return nil, fmt.Errorf("OTEL_TRACES_EXPORTER must be %s, %s or %s", TracesNone, TracesOTLP, TracesStdout)
+	}
+
+	if cfg.QuestionTimeout, err = parseDuration("QUESTION_TIMEOUT", 15*time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.QuestionMaxAttempts, err = parseInt("QUESTION_MAX_ATTEMPTS", 3); err != nil {
+		return nil, err
+	}
+	if cfg.QuestionMaxAttempts < 1 {
+		return nil, errors.New("QUESTION_MAX_ATTEMPTS must be at least 1")
+	}
+	if cfg.WatchdogInterval, err = parseDuration("WATCHDOG_INTERVAL", time.Minute); err != nil {
+		return nil, err
+	}
+
+	if cfg.RetryMaxAttempts, err = parseInt("RETRY_MAX_ATTEMPTS", 5); err != nil {
+		return nil, err
+	}
+	if cfg.RetryInitialBackoff, err = parseDuration("RETRY_INITIAL_BACKOFF", time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.RetryMaxBackoff, err = parseDuration("RETRY_MAX_BACKOFF", 5*time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.DedupeTTL, err = parseDuration("DEDUPE_TTL", 7*24*time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.OutboxPollInterval, err = parseDuration("OUTBOX_POLL_INTERVAL", time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.OutboxMaxAttempts, err = parseInt("OUTBOX_MAX_ATTEMPTS", 20); err != nil {
+		return nil, err
+	}
+	if cfg.OutboxMaxAttempts < 1 {
+		return nil, errors.New("OUTBOX_MAX_ATTEMPTS must be at least 1")
+	}
+	if cfg.LeaderLeaseTTL, err = parseDuration("LEADER_LEASE_TTL", 30*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.LeaderLeaseTTL < 3*time.Second {
+		return nil, errors.New("LEADER_LEASE_TTL must be at least 3s")
+	}
+	if cfg.KafkaWorkers, err = parseInt("KAFKA_WORKERS", 8); err != nil {
+		return nil, err
+	}
+	if cfg.KafkaWorkers < 1 {
+		return nil, errors.New("KAFKA_WORKERS must be at least 1")
+	}
+	if cfg.ShutdownTimeout, err = parseDuration("SHUTDOWN_TIMEOUT", 30*time.Second); err != nil {
+		return nil, err
+	}
+	if v, ok := parseBool(os.Getenv("LEADER_ELECTION")); ok {
+		cfg.LeaderElection = v
+	} else {
+		cfg.LeaderElection = true
+	}
+
 	if len(cfg.KafkaBrokers) == 0 {
 		return nil, errors.New("KAFKA_BOOTSTRAP_SERVERS is required")
 	}
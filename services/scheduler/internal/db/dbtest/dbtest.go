@@ -0,0 +1,389 @@
+// Package dbtest is the behaviour every db.Store implementation must share.
+// The MongoDB and in-memory stores both run it, so the in-memory store used
+// by tests and the e2e harness cannot drift from production.
+package dbtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"llm-your-business/schemas/events"
+	model "llm-your-business/services/go/models"
+	"llm-your-business/services/scheduler/internal/db"
+)
+
+// Store is a db.Store the suite can read executions back from.
+type Store interface {
+	db.Store
+	// Execution returns the execution tracked for manifestID.
+	Execution(manifestID string) (db.Execution, bool)
+}
+
+// Run runs the suite. newStore returns an empty store for each subtest.
+func Run(t *testing.T, newStore func(t *testing.T) Store) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s Store)
+	}{
+		{"ExecutionInOrder", testExecutionInOrder},
+		{"ExecutionOutOfOrder", testExecutionOutOfOrder},
+		{"RecordDatapoint", testRecordDatapoint},
+		{"Watchdog", testWatchdog},
+		{"FailExpiredExecutions", testFailExpiredExecutions},
+		{"ClaimEvent", testClaimEvent},
+		{"Outbox", testOutbox},
+		{"Leases", testLeases},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) { tt.fn(t, newStore(t)) })
+	}
+}
+
+const (
+	manifestID  = "m-1"
+	executionID = "e-1"
+	objectiveID = "65f000000000000000000001"
+)
+
+var deadline = time.Now().UTC().Add(time.Hour).Truncate(time.Millisecond)
+
+func manifest(questionIDs ...string) events.ObjectiveManifestV1Json {
+	evt := events.ObjectiveManifestV1Json{Meta: events.ObjectiveManifestV1JsonMeta{
+		SchemaVersion: 1, Producer: "test", ManifestId: manifestID, ExecutionId: executionID, ObjectiveId: objectiveID,
+	}}
+	for _, id := range questionIDs {
+		evt.Data.Questions = append(evt.Data.Questions, events.ObjectiveManifestV1JsonDataQuestionsElem{QuestionId: id})
+	}
+	return evt
+}
+
+func question(id string) events.ObjectiveExecutionQuestionV1Json {
+	return events.ObjectiveExecutionQuestionV1Json{
+		Meta: events.ObjectiveExecutionQuestionV1JsonMeta{
+			SchemaVersion: 1, Producer: "test", RunAttempt: 1, ManifestId: manifestID, ExecutionId: executionID,
+			ObjectiveId: objectiveID, QuestionId: id, QuestionType: events.QuestionTypeTop5,
+		},
+		Data: events.ObjectiveExecutionQuestionV1JsonData{Prompt: "Which tools are best?"},
+	}
+}
+
+func answer(id string) events.ObjectiveExecutionAnswerV1Json {
+	return events.ObjectiveExecutionAnswerV1Json{
+		Meta: events.ObjectiveExecutionAnswerV1JsonMeta{
+			SchemaVersion: 1, Producer: "test", RunAttempt: 1, ManifestId: manifestID, ExecutionId: executionID,
+			ObjectiveId: objectiveID, QuestionId: id,
+		},
+		Data: events.ObjectiveExecutionAnswerV1JsonData{AnswerText: "1. Acme", FinishReason: "STOP"},
+	}
+}
+
+func datapoint(id string) events.ObjectiveDatapointV1Json {
+	return events.ObjectiveDatapointV1Json{Meta: events.ObjectiveDatapointV1JsonMeta{
+		SchemaVersion: 1, Producer: "test", RunAttempt: 1, ManifestId: manifestID, ExecutionId: executionID, QuestionId: id,
+	}}
+}
+
+// execution returns the tracked execution, failing t when there is none.
+func execution(t *testing.T, s Store) db.Execution {
+	t.Helper()
+	exec, ok := s.Execution(manifestID)
+	if !ok {
+		t.Fatalf("no execution for %s", manifestID)
+	}
+	return exec
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func saveAnswer(t *testing.T, s Store, id string, wantNew bool) {
+	t.Helper()
+	ok, err := s.SaveAnswer(context.Background(), answer(id), deadline)
+	must(t, err)
+	if ok != wantNew {
+		t.Fatalf("SaveAnswer(%s) = %v, want %v", id, ok, wantNew)
+	}
+}
+
+func testExecutionInOrder(t *testing.T, s Store) {
+	ctx := context.Background()
+	must(t, s.RecordManifest(ctx, manifest("q1", "q2"), deadline))
+	if exec := execution(t, s); exec.Status != db.ExecutionStatusPending || exec.ExpectedAnswers != 2 || !exec.DeadlineAt.Equal(deadline) {
+		t.Fatalf("after manifest: %+v", exec)
+	}
+	must(t, s.SaveQuestion(ctx, question("q1"), deadline))
+	must(t, s.SaveQuestion(ctx, question("q1"), deadline)) // redelivered
+	must(t, s.SaveQuestion(ctx, question("q2"), deadline))
+	if exec := execution(t, s); exec.Status != db.ExecutionStatusProcessing || exec.QuestionsAsked != 2 || exec.StartedAt == nil {
+		t.Fatalf("after questions: %+v", exec)
+	}
+	if qt, err := s.FindQuestionType(ctx, manifestID, "q1"); err != nil || qt != events.QuestionTypeTop5 {
+		t.Fatalf("FindQuestionType = %q, %v", qt, err)
+	}
+
+	saveAnswer(t, s, "q1", true)
+	saveAnswer(t, s, "q1", false) // redelivered: not counted again
+	if exec := execution(t, s); exec.Status != db.ExecutionStatusProcessing || exec.ReceivedAnswers != 1 {
+		t.Fatalf("after one answer: %+v", exec)
+	}
+	saveAnswer(t, s, "q2", true)
+	exec := execution(t, s)
+	if exec.Status != db.ExecutionStatusCompleted || exec.ReceivedAnswers != 2 || exec.CompletedAt == nil {
+		t.Fatalf("after all answers: %+v", exec)
+	}
+	var statuses []string
+	for _, h := range exec.History {
+		statuses = append(statuses, h.Status)
+	}
+	want := []string{db.ExecutionStatusPending, db.ExecutionStatusProcessing, db.ExecutionStatusCompleted}
+	if len(statuses) != len(want) || statuses[0] != want[0] || statuses[1] != want[1] || statuses[2] != want[2] {
+		t.Fatalf("history %v, want %v", statuses, want)
+	}
+}
+
+func testExecutionOutOfOrder(t *testing.T, s Store) {
+	ctx := context.Background()
+	early := time.Now().UTC().Add(time.Minute).Truncate(time.Millisecond)
+
+	// The answer beats its question, and both beat the manifest.
+	ok, err := s.SaveAnswer(ctx, answer("q1"), early)
+	must(t, err)
+	if !ok {
+		t.Fatal("first answer not stored")
+	}
+	if exec := execution(t, s); exec.Status != db.ExecutionStatusPending || exec.ReceivedAnswers != 1 || !exec.DeadlineAt.Equal(early) {
+		t.Fatalf("after early answer: %+v", exec)
+	}
+	must(t, s.SaveQuestion(ctx, question("q1"), early))
+	stalled, err := s.FindStalledQuestions(ctx, time.Now().Add(time.Hour), 10)
+	must(t, err)
+	if len(stalled) != 0 {
+		t.Fatalf("answered question reported stalled: %+v", stalled)
+	}
+
+	// The manifest sets the real deadline and completes the execution.
+	must(t, s.RecordManifest(ctx, manifest("q1"), deadline))
+	exec := execution(t, s)
+	if exec.Status != db.ExecutionStatusCompleted || exec.QuestionsAsked != 1 || exec.ExecutionId != executionID || !exec.DeadlineAt.Equal(deadline) {
+		t.Fatalf("after manifest: %+v", exec)
+	}
+}
+
+func testRecordDatapoint(t *testing.T, s Store) {
+	ctx := context.Background()
+	must(t, s.RecordManifest(ctx, manifest("q1"), deadline))
+
+	_, err := s.RecordDatapoint(ctx, datapoint("q1"))
+	var terr *db.TransitionError
+	if !errors.As(err, &terr) || terr.From != "" || !errors.Is(err, db.ErrIllegalTransition) {
+		t.Fatalf("datapoint of an unrecorded question: %v", err)
+	}
+	must(t, s.SaveQuestion(ctx, question("q1"), deadline))
+	_, err = s.RecordDatapoint(ctx, datapoint("q1"))
+	if !errors.As(err, &terr) || terr.From != db.QuestionStatusPending {
+		t.Fatalf("datapoint of an unanswered question: %v", err)
+	}
+
+	saveAnswer(t, s, "q1", true)
+	ok, err := s.RecordDatapoint(ctx, datapoint("q1"))
+	must(t, err)
+	if !ok {
+		t.Fatal("first datapoint not counted")
+	}
+	ok, err = s.RecordDatapoint(ctx, datapoint("q1"))
+	must(t, err)
+	if ok {
+		t.Fatal("second datapoint counted")
+	}
+	if exec := execution(t, s); exec.Datapoints != 1 {
+		t.Fatalf("datapoints = %d, want 1", exec.Datapoints)
+	}
+}
+
+func testWatchdog(t *testing.T, s Store) {
+	ctx := context.Background()
+	must(t, s.RecordManifest(ctx, manifest("q1", "q2"), deadline))
+	must(t, s.SaveQuestion(ctx, question("q1"), deadline))
+	must(t, s.SaveQuestion(ctx, question("q2"), deadline))
+
+	if stalled, err := s.FindStalledQuestions(ctx, time.Now().Add(-time.Minute), 10); err != nil || len(stalled) != 0 {
+		t.Fatalf("questions asked just now reported stalled: %+v, %v", stalled, err)
+	}
+	stalled, err := s.FindStalledQuestions(ctx, time.Now().Add(time.Minute), 1)
+	must(t, err)
+	if len(stalled) != 1 || stalled[0].RunAttempt != 1 || stalled[0].ExecutionStatus != db.ExecutionStatusProcessing {
+		t.Fatalf("stalled = %+v, want one question of a PROCESSING execution", stalled)
+	}
+	q := stalled[0]
+
+	msg := db.OutboxMessage{Topic: "objective.execution.question", Key: executionID, Payload: "{}"}
+	if ok, err := s.RetryQuestion(ctx, q, msg); err != nil || !ok {
+		t.Fatalf("RetryQuestion = %v, %v", ok, err)
+	}
+	if ok, err := s.RetryQuestion(ctx, q, msg); err != nil || ok {
+		t.Fatalf("retrying the same attempt again = %v, %v", ok, err)
+	}
+	pending, err := s.PendingOutbox(ctx, 10, nil)
+	must(t, err)
+	if len(pending) != 1 {
+		t.Fatalf("outbox has %d messages, want 1", len(pending))
+	}
+
+	// The other question is answered; the retried one fails, which fails the
+	// execution.
+	other := "q1"
+	if q.QuestionId == "q1" {
+		other = "q2"
+	}
+	saveAnswer(t, s, other, true)
+	q.RunAttempt++
+	must(t, s.FailQuestion(ctx, q, "no answer"))
+	must(t, s.FailQuestion(ctx, q, "no answer")) // already failed: not counted again
+	exec := execution(t, s)
+	if exec.Status != db.ExecutionStatusFailed || exec.FailedQuestions != 1 {
+		t.Fatalf("after failing the last question: %+v", exec)
+	}
+
+	// A late answer is still stored and counted.
+	saveAnswer(t, s, q.QuestionId, true)
+	if exec := execution(t, s); exec.Status != db.ExecutionStatusFailed || exec.ReceivedAnswers != 2 {
+		t.Fatalf("after a late answer: %+v", exec)
+	}
+	if ok, err := s.RecordDatapoint(ctx, datapoint(q.QuestionId)); err != nil || !ok {
+		t.Fatalf("datapoint of a late answer = %v, %v", ok, err)
+	}
+}
+
+func testFailExpiredExecutions(t *testing.T, s Store) {
+	ctx := context.Background()
+	must(t, s.RecordManifest(ctx, manifest("q1"), deadline))
+	if n, err := s.FailExpiredExecutions(ctx, deadline.Add(-time.Second)); err != nil || n != 0 {
+		t.Fatalf("before the deadline: %d, %v", n, err)
+	}
+	if n, err := s.FailExpiredExecutions(ctx, deadline.Add(time.Second)); err != nil || n != 1 {
+		t.Fatalf("after the deadline: %d, %v", n, err)
+	}
+	if exec := execution(t, s); exec.Status != db.ExecutionStatusFailed || exec.FailureReason == "" || exec.CompletedAt == nil {
+		t.Fatalf("expired execution: %+v", exec)
+	}
+	if n, err := s.FailExpiredExecutions(ctx, deadline.Add(time.Second)); err != nil || n != 0 {
+		t.Fatalf("failing again: %d, %v", n, err)
+	}
+	// Nothing moves a final execution.
+	saveAnswer(t, s, "q1", true)
+	if exec := execution(t, s); exec.Status != db.ExecutionStatusFailed {
+		t.Fatalf("answer moved a FAILED execution to %s", exec.Status)
+	}
+}
+
+func testClaimEvent(t *testing.T, s Store) {
+	ctx := context.Background()
+	claim := func(stale time.Duration, want db.ClaimState) {
+		t.Helper()
+		got, err := s.ClaimEvent(ctx, "answer", executionID, "q1", 1, stale)
+		must(t, err)
+		if got != want {
+			t.Fatalf("ClaimEvent = %v, want %v", got, want)
+		}
+	}
+	claim(time.Minute, db.ClaimAcquired)
+	claim(time.Minute, db.ClaimHeld)
+	if got, err := s.ClaimEvent(ctx, "answer", executionID, "q1", 2, time.Minute); err != nil || got != db.ClaimAcquired {
+		t.Fatalf("another run_attempt = %v, %v", got, err)
+	}
+
+	// A failed handler releases its claim; a redelivery claims it again.
+	must(t, s.ReleaseEvent(ctx, "answer", executionID, "q1", 1))
+	claim(time.Minute, db.ClaimAcquired)
+
+	// A claim older than stale was abandoned and is taken over.
+	time.Sleep(5 * time.Millisecond)
+	claim(time.Millisecond, db.ClaimAcquired)
+
+	must(t, s.MarkProcessed(ctx, "answer", executionID, "q1", 1))
+	must(t, s.MarkProcessed(ctx, "answer", executionID, "q1", 1))
+	claim(time.Minute, db.ClaimDone)
+	claim(0, db.ClaimDone)
+	must(t, s.ReleaseEvent(ctx, "answer", executionID, "q1", 1))
+	claim(time.Minute, db.ClaimDone)
+}
+
+func testOutbox(t *testing.T, s Store) {
+	ctx := context.Background()
+	run := model.ObjectiveV1JsonRunsElem{Timestamp: time.Now().UTC(), ManifestId: manifestID}
+	must(t, s.EnqueueObjectiveRun(ctx, objectiveID, run, []db.OutboxMessage{
+		{Topic: "objective.manifest", Key: "e-1", Payload: "manifest"},
+		{Topic: "objective.execution.question", Key: "e-1", Payload: "q1"},
+		{Topic: "objective.manifest", Key: "e-2", Payload: "manifest"},
+	}))
+	obj, err := s.FindObjective(ctx, objectiveID)
+	must(t, err)
+	if len(obj.Runs) != 1 || obj.Runs[0].ManifestId != manifestID {
+		t.Fatalf("runs = %+v", obj.Runs)
+	}
+
+	pending := func(skip ...string) []db.OutboxMessage {
+		t.Helper()
+		msgs, err := s.PendingOutbox(ctx, 10, skip)
+		must(t, err)
+		return msgs
+	}
+	msgs := pending()
+	if len(msgs) != 3 || msgs[0].Payload != "manifest" || msgs[1].Payload != "q1" || msgs[0].Status != db.OutboxPending {
+		t.Fatalf("pending = %+v", msgs)
+	}
+	if msgs := pending("e-1"); len(msgs) != 1 || msgs[0].Key != "e-2" {
+		t.Fatalf("pending without e-1 = %+v", msgs)
+	}
+
+	retryAt := time.Now().UTC().Add(time.Minute).Truncate(time.Millisecond)
+	must(t, s.MarkOutboxFailed(ctx, msgs[0].ID, errors.New("broker down"), retryAt))
+	msgs = pending()
+	if msgs[0].Attempts != 1 || msgs[0].LastError != "broker down" || msgs[0].NextAttemptAt == nil || !msgs[0].NextAttemptAt.Equal(retryAt) {
+		t.Fatalf("after a failed attempt: %+v", msgs[0])
+	}
+
+	// Parking a manifest takes the rest of its execution's entries with it.
+	must(t, s.ParkOutbox(ctx, msgs[0].ID, db.OutboxFailed, errors.New("gave up")))
+	if n, err := s.ParkOutboxKey(ctx, "e-1", db.OutboxFailed, errors.New("manifest parked")); err != nil || n != 1 {
+		t.Fatalf("ParkOutboxKey = %d, %v", n, err)
+	}
+	msgs = pending()
+	if len(msgs) != 1 || msgs[0].Key != "e-2" {
+		t.Fatalf("pending after parking e-1 = %+v", msgs)
+	}
+	must(t, s.MarkOutboxSent(ctx, msgs[0].ID))
+	if msgs := pending(); len(msgs) != 0 {
+		t.Fatalf("pending after sending = %+v", msgs)
+	}
+	must(t, s.MarkOutboxSent(ctx, primitive.NewObjectID()))
+}
+
+func testLeases(t *testing.T, s Store) {
+	ctx := context.Background()
+	acquire := func(holder string, ttl time.Duration, want bool) {
+		t.Helper()
+		got, err := s.AcquireLease(ctx, "scheduler", holder, ttl)
+		must(t, err)
+		if got != want {
+			t.Fatalf("AcquireLease(%s) = %v, want %v", holder, got, want)
+		}
+	}
+	acquire("a", time.Minute, true)
+	acquire("a", time.Minute, true) // renewal
+	acquire("b", time.Minute, false)
+	must(t, s.ReleaseLease(ctx, "scheduler", "b")) // not the holder: no effect
+	acquire("b", time.Minute, false)
+	must(t, s.ReleaseLease(ctx, "scheduler", "a"))
+	acquire("b", time.Millisecond, true)
+	time.Sleep(5 * time.Millisecond)
+	acquire("a", time.Minute, true) // b's lease expired
+}
@@ -0,0 +1,133 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	collProcessedEvents = "processed_events"
+	processedTTLIndex   = "processed_at_ttl"
+	// Mongo error code for an existing index with different options.
+	codeIndexOptionsConflict = 85
+)
+
+// EnsureProcessedEventsTTL creates (or retunes) the TTL index that expires
+// dedupe records after ttl.
+func (c *Client) EnsureProcessedEventsTTL(ctx context.Context, ttl time.Duration) error {
+	secs := int32(ttl / time.Second)
+	coll := c.db.Collection(collProcessedEvents)
+	_, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "processed_at", Value: 1}},
+		Options: options.Index().SetName(processedTTLIndex).SetExpireAfterSeconds(secs),
+	})
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Code == codeIndexOptionsConflict {
+		// TTL changed since the index was created; update it in place.
+		err = c.db.RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: collProcessedEvents},
+			{Key: "index", Value: bson.D{{Key: "name", Value: processedTTLIndex}, {Key: "expireAfterSeconds", Value: secs}}},
+		}).Err()
+	}
+	if err != nil {
+		return fmt.Errorf("processed_events ttl index: %w", err)
+	}
+	return nil
+}
+
+func processedKey(kind, executionID, questionID string, runAttempt int) string {
+	return fmt.Sprintf("%s:%s:%s:%d", kind, executionID, questionID, runAttempt)
+}
+
+// ClaimState is the outcome of ClaimEvent.
+type ClaimState int
+
+const (
+	// ClaimAcquired: the caller holds the claim and must MarkProcessed or
+	// ReleaseEvent it.
+	ClaimAcquired ClaimState = iota
+	// ClaimDone: the event was already handled.
+	ClaimDone
+	// ClaimHeld: another copy of the event is being handled.
+	ClaimHeld
+)
+
+// processed_events status values. Records written before claims existed have
+// no status and count as processed.
+const (
+	processedClaimed = "CLAIMED"
+	processedDone    = "PROCESSED"
+)
+
+// ClaimEvent claims an event of kind with the given (execution_id,
+// question_id, run_attempt) before it is handled, so that two copies handled
+// at once cannot both pass. A claim older than stale was left by a consumer
+// that died mid-handler and is taken over.
+func (c *Client) ClaimEvent(ctx context.Context, kind, executionID, questionID string, runAttempt int, stale time.Duration) (ClaimState, error) {
+	now := time.Now().UTC()
+	key := processedKey(kind, executionID, questionID, runAttempt)
+	coll := c.db.Collection(collProcessedEvents)
+	filter := bson.M{"_id": key, "status": processedClaimed, "claimed_at": bson.M{"$lt": now.Add(-stale)}}
+	update := bson.M{"$set": bson.M{
+		"kind":         kind,
+		"execution_id": executionID,
+		"question_id":  questionID,
+		"run_attempt":  runAttempt,
+		"status":       processedClaimed,
+		"claimed_at":   now,
+		"processed_at": now, // lets the TTL index expire claims never released
+	}}
+	_, err := coll.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err == nil {
+		return ClaimAcquired, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return 0, err
+	}
+	// The record exists and is either processed or freshly claimed.
+	var doc struct {
+		Status string `bson:"status"`
+	}
+	err = coll.FindOne(ctx, bson.M{"_id": key}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return ClaimHeld, nil // released meanwhile; the caller retries
+	}
+	if err != nil {
+		return 0, err
+	}
+	if doc.Status == processedClaimed {
+		return ClaimHeld, nil
+	}
+	return ClaimDone, nil
+}
+
+// MarkProcessed records that a claimed event was handled. Marking twice is a
+// no-op.
+func (c *Client) MarkProcessed(ctx context.Context, kind, executionID, questionID string, runAttempt int) error {
+	key := processedKey(kind, executionID, questionID, runAttempt)
+	update := bson.M{
+		"$set": bson.M{"status": processedDone, "processed_at": time.Now().UTC()},
+		"$setOnInsert": bson.M{
+			"kind":         kind,
+			"execution_id": executionID,
+			"question_id":  questionID,
+			"run_attempt":  runAttempt,
+		},
+	}
+	_, err := c.db.Collection(collProcessedEvents).UpdateOne(ctx, bson.M{"_id": key}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// ReleaseEvent drops a claim whose handler failed, so a redelivery can claim
+// the event again.
+func (c *Client) ReleaseEvent(ctx context.Context, kind, executionID, questionID string, runAttempt int) error {
+	key := processedKey(kind, executionID, questionID, runAttempt)
+	_, err := c.db.Collection(collProcessedEvents).DeleteOne(ctx, bson.M{"_id": key, "status": processedClaimed})
+	return err
+}
@@ -0,0 +1,419 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"llm-your-business/schemas/events"
+)
+
+// Collections owned by the scheduler. They are kept separate from the
+// Prisma-managed "executions"/"answers" collections, which use ObjectId refs.
+const (
+	collExecutions = "objective_executions"
+	collAnswers    = "objective_answers"
+	collQuestions  = "objective_questions"
+)
+
+// Execution status values mirror the Prisma ExecutionStatus enum. See
+// lifecycle.go for the transitions between them.
+const (
+	ExecutionStatusPending    = "PENDING"
+	ExecutionStatusProcessing = "PROCESSING"
+	ExecutionStatusCompleted  = "COMPLETED"
+	ExecutionStatusFailed     = "FAILED"
+)
+
+// Question status values in objective_questions.
+const (
+	QuestionStatusPending   = "PENDING"
+	QuestionStatusAnswered  = "ANSWERED"
+	QuestionStatusExtracted = "EXTRACTED"
+	QuestionStatusFailed    = "FAILED"
+)
+
+// Execution is the per-manifest tracking document. History lists every
+// status the execution entered, oldest first; CompletedAt is set when it
+// entered COMPLETED or FAILED.
+type Execution struct {
+	ManifestId      string         `bson:"manifest_id" json:"manifest_id"`
+	ExecutionId     string         `bson:"execution_id" json:"execution_id"`
+	ObjectiveId     string         `bson:"objective_id" json:"objective_id"`
+	QuestionIds     []string       `bson:"question_ids" json:"question_ids"`
+	ExpectedAnswers int            `bson:"expected_answers" json:"expected_answers"`
+	QuestionsAsked  int            `bson:"questions_asked" json:"questions_asked"`
+	ReceivedAnswers int            `bson:"received_answers" json:"received_answers"`
+	Datapoints      int            `bson:"datapoints" json:"datapoints"`
+	FailedQuestions int            `bson:"failed_questions,omitempty" json:"failed_questions,omitempty"`
+	Status          string         `bson:"status" json:"status"`
+	FailureReason   string         `bson:"failure_reason,omitempty" json:"failure_reason,omitempty"`
+	History         []StatusChange `bson:"history" json:"history"`
+	DeadlineAt      time.Time      `bson:"deadline_at" json:"deadline_at"`
+	CreatedAt       time.Time      `bson:"created_at" json:"created_at"`
+	StartedAt       *time.Time     `bson:"started_at,omitempty" json:"started_at,omitempty"`
+	UpdatedAt       time.Time      `bson:"updated_at" json:"updated_at"`
+	CompletedAt     *time.Time     `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+}
+
+// ensureExecutionIndexes creates the unique keys the upserts below rely on.
+func (c *Client) ensureExecutionIndexes(ctx context.Context) error {
+	if _, err := c.db.Collection(collExecutions).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "manifest_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		return fmt.Errorf("executions index: %w", err)
+	}
+	if _, err := c.db.Collection(collAnswers).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "manifest_id", Value: 1}, {Key: "question_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		return fmt.Errorf("answers index: %w", err)
+	}
+	if _, err := c.db.Collection(collQuestions).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "manifest_id", Value: 1}, {Key: "question_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		return fmt.Errorf("questions index: %w", err)
+	}
+	if _, err := c.db.Collection(collQuestions).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "status", Value: 1}, {Key: "attempted_at", Value: 1}},
+	}); err != nil {
+		return fmt.Errorf("questions watchdog index: %w", err)
+	}
+	return nil
+}
+
+// RecordManifest creates (or fills in) the execution document for a manifest.
+// Questions and answers may arrive before their manifest, so the document can
+// already exist with counts and a status; those are preserved.
+func (c *Client) RecordManifest(ctx context.Context, evt events.ObjectiveManifestV1Json, deadline time.Time) error {
+	now := time.Now().UTC()
+	ids := make([]string, 0, len(evt.Data.Questions))
+	for _, q := range evt.Data.Questions {
+		ids = append(ids, q.QuestionId)
+	}
+	filter := bson.M{"manifest_id": evt.Meta.ManifestId}
+	update := bson.M{
+		"$set": bson.M{
+			"execution_id":     evt.Meta.ExecutionId,
+			"objective_id":     evt.Meta.ObjectiveId,
+			"question_ids":     ids,
+			"expected_answers": len(ids),
+			"deadline_at":      deadline.UTC(),
+			"updated_at":       now,
+		},
+		"$setOnInsert": pendingExecution(now, "manifest recorded"),
+	}
+	opts := options.Update().SetUpsert(true)
+	if _, err := c.db.Collection(collExecutions).UpdateOne(ctx, filter, update, opts); err != nil {
+		return fmt.Errorf("upsert execution: %w", err)
+	}
+	return c.completeIfAnswered(ctx, evt.Meta.ManifestId, now)
+}
+
+// pendingExecution returns the $setOnInsert fields of an execution document
+// created in PENDING by an upsert. Counters are left out: $inc creates them.
+// Upserts by an event that beat its manifest add a fallback deadline_at, so
+// the execution still expires if the manifest never arrives; the manifest
+// overwrites it.
+func pendingExecution(now time.Time, reason string) bson.M {
+	return bson.M{
+		"status":     ExecutionStatusPending,
+		"history":    []StatusChange{{Status: ExecutionStatusPending, At: now, Reason: reason}},
+		"created_at": now,
+	}
+}
+
+// withTransaction runs fn in a transaction; the driver retries it on
+// transient errors. fn must use sc for every operation.
+func (c *Client) withTransaction(ctx context.Context, fn func(sc mongo.SessionContext) (interface{}, error)) (interface{}, error) {
+	sess, err := c.client.StartSession()
+	if err != nil {
+		return nil, fmt.Errorf("start session: %w", err)
+	}
+	defer sess.EndSession(ctx)
+	return sess.WithTransaction(ctx, fn)
+}
+
+// transitionExecution moves the manifest's execution to status to when the
+// lifecycle allows it from the current status and match, if not nil, holds
+// too. set lists further fields to set. It returns false when the execution
+// was not moved.
+func (c *Client) transitionExecution(ctx context.Context, manifestID, to, reason string, now time.Time, match, set bson.M) (bool, error) {
+	filter := bson.M{"manifest_id": manifestID, "status": bson.M{"$in": executionSources(to)}}
+	for k, v := range match {
+		filter[k] = v
+	}
+	fields := bson.M{"status": to, "updated_at": now}
+	for k, v := range set {
+		fields[k] = v
+	}
+	update := bson.M{
+		"$set":  fields,
+		"$push": bson.M{"history": StatusChange{Status: to, At: now, Reason: reason}},
+	}
+	res, err := c.db.Collection(collExecutions).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+// SaveQuestion stores the question event once per (manifest_id, question_id) so
+// later stages can recover its question_type and prompt, and the watchdog can
+// re-emit it. Re-emitted attempts find the document already there. The first
+// question recorded moves its execution from PENDING to PROCESSING. The
+// question, its answered status and the execution are written in one
+// transaction, so a failure part way leaves the redelivered question to do
+// all of it. An execution created here gets deadline until its manifest sets
+// the real one.
+func (c *Client) SaveQuestion(ctx context.Context, evt events.ObjectiveExecutionQuestionV1Json, deadline time.Time) error {
+	now := time.Now().UTC()
+	meta, err := ToBSONM(evt.Meta)
+	if err != nil {
+		return fmt.Errorf("convert question meta: %w", err)
+	}
+	data, err := ToBSONM(evt.Data)
+	if err != nil {
+		return fmt.Errorf("convert question data: %w", err)
+	}
+	filter := bson.M{"manifest_id": evt.Meta.ManifestId, "question_id": evt.Meta.QuestionId}
+	update := bson.M{"$setOnInsert": bson.M{
+		"manifest_id":   evt.Meta.ManifestId,
+		"execution_id":  evt.Meta.ExecutionId,
+		"question_id":   evt.Meta.QuestionId,
+		"question_type": string(evt.Meta.QuestionType),
+		"meta":          meta,
+		"data":          data,
+		"run_attempt":   evt.Meta.RunAttempt,
+		"status":        QuestionStatusPending,
+		"attempted_at":  now,
+		"received_at":   now,
+	}}
+	_, err = c.withTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		res, err := c.db.Collection(collQuestions).UpdateOne(sc, filter, update, options.Update().SetUpsert(true))
+		if err != nil {
+			return nil, fmt.Errorf("upsert question: %w", err)
+		}
+		if res.UpsertedCount == 0 {
+			return nil, nil
+		}
+
+		// The answer may have been handled before the question was recorded;
+		// its SaveAnswer found no question to mark, so mark it here. An answer
+		// stored concurrently also updates the execution below, so one of the
+		// two transactions conflicts and is retried after the other.
+		answered, err := c.db.Collection(collAnswers).CountDocuments(sc, filter, options.Count().SetLimit(1))
+		if err != nil {
+			return nil, fmt.Errorf("find answer: %w", err)
+		}
+		if answered > 0 {
+			questionFilter := bson.M{
+				"manifest_id": evt.Meta.ManifestId,
+				"question_id": evt.Meta.QuestionId,
+				"status":      bson.M{"$in": questionSources(QuestionStatusAnswered)},
+			}
+			if _, err := c.db.Collection(collQuestions).UpdateOne(sc, questionFilter,
+				bson.M{"$set": bson.M{"status": QuestionStatusAnswered, "answered_at": now}}); err != nil {
+				return nil, fmt.Errorf("mark question answered: %w", err)
+			}
+		}
+
+		// Upsert so a question that beats its manifest is counted; the manifest
+		// fills in the remaining fields.
+		onInsert := pendingExecution(now, "question before manifest")
+		onInsert["execution_id"] = evt.Meta.ExecutionId
+		onInsert["objective_id"] = evt.Meta.ObjectiveId
+		onInsert["deadline_at"] = deadline.UTC()
+		execUpdate := bson.M{
+			"$inc":         bson.M{"questions_asked": 1},
+			"$set":         bson.M{"updated_at": now},
+			"$setOnInsert": onInsert,
+		}
+		if _, err := c.db.Collection(collExecutions).UpdateOne(sc, bson.M{"manifest_id": evt.Meta.ManifestId}, execUpdate, options.Update().SetUpsert(true)); err != nil {
+			return nil, fmt.Errorf("count question: %w", err)
+		}
+		if _, err := c.transitionExecution(sc, evt.Meta.ManifestId, ExecutionStatusProcessing, "first question recorded", now, nil, bson.M{"started_at": now}); err != nil {
+			return nil, fmt.Errorf("start execution: %w", err)
+		}
+		return nil, nil
+	})
+	return err
+}
+
+// FindQuestionType returns the question_type recorded for a question, or ""
+// when the question event has not been seen yet.
+func (c *Client) FindQuestionType(ctx context.Context, manifestID, questionID string) (events.QuestionType, error) {
+	var doc struct {
+		QuestionType string `bson:"question_type"`
+	}
+	filter := bson.M{"manifest_id": manifestID, "question_id": questionID}
+	err := c.db.Collection(collQuestions).FindOne(ctx, filter).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return events.QuestionType(doc.QuestionType), nil
+}
+
+// SaveAnswer stores an answer once per (manifest_id, question_id) and bumps
+// the execution's received count. Returns false when the answer was already
+// stored, in which case nothing is counted. The answer, its question's status
+// and the count are written in one transaction: a failure part way stores
+// none of them, so the redelivered answer is counted. An execution created
+// here gets deadline until its manifest sets the real one.
+func (c *Client) SaveAnswer(ctx context.Context, evt events.ObjectiveExecutionAnswerV1Json, deadline time.Time) (bool, error) {
+	now := time.Now().UTC()
+	meta, err := ToBSONM(evt.Meta)
+	if err != nil {
+		return false, fmt.Errorf("convert answer meta: %w", err)
+	}
+	data, err := ToBSONM(evt.Data)
+	if err != nil {
+		return false, fmt.Errorf("convert answer data: %w", err)
+	}
+	filter := bson.M{"manifest_id": evt.Meta.ManifestId, "question_id": evt.Meta.QuestionId}
+	update := bson.M{"$setOnInsert": bson.M{
+		"manifest_id":  evt.Meta.ManifestId,
+		"execution_id": evt.Meta.ExecutionId,
+		"objective_id": evt.Meta.ObjectiveId,
+		"question_id":  evt.Meta.QuestionId,
+		"meta":         meta,
+		"data":         data,
+		"received_at":  now,
+	}}
+	inserted, err := c.withTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		res, err := c.db.Collection(collAnswers).UpdateOne(sc, filter, update, options.Update().SetUpsert(true))
+		if err != nil {
+			return false, fmt.Errorf("upsert answer: %w", err)
+		}
+		if res.UpsertedCount == 0 {
+			return false, nil
+		}
+
+		// Stop the watchdog from re-emitting the question. A late answer to a
+		// question that already failed still counts below.
+		questionFilter := bson.M{
+			"manifest_id": evt.Meta.ManifestId,
+			"question_id": evt.Meta.QuestionId,
+			"status":      bson.M{"$in": questionSources(QuestionStatusAnswered)},
+		}
+		if _, err := c.db.Collection(collQuestions).UpdateOne(sc, questionFilter,
+			bson.M{"$set": bson.M{"status": QuestionStatusAnswered, "answered_at": now}}); err != nil {
+			return false, fmt.Errorf("mark question answered: %w", err)
+		}
+
+		// Count the answer against its execution. Upsert so an answer that beats
+		// its manifest is not lost; the manifest fills in the remaining fields.
+		onInsert := pendingExecution(now, "answer before manifest")
+		onInsert["execution_id"] = evt.Meta.ExecutionId
+		onInsert["objective_id"] = evt.Meta.ObjectiveId
+		onInsert["deadline_at"] = deadline.UTC()
+		execUpdate := bson.M{
+			"$inc":         bson.M{"received_answers": 1},
+			"$set":         bson.M{"updated_at": now},
+			"$setOnInsert": onInsert,
+		}
+		if _, err := c.db.Collection(collExecutions).UpdateOne(sc, bson.M{"manifest_id": evt.Meta.ManifestId}, execUpdate, options.Update().SetUpsert(true)); err != nil {
+			return false, fmt.Errorf("count answer: %w", err)
+		}
+		if err := c.completeIfAnswered(sc, evt.Meta.ManifestId, now); err != nil {
+			return false, err
+		}
+		return true, nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return inserted.(bool), nil
+}
+
+// completeIfAnswered flips an in-flight execution to COMPLETED once every
+// expected answer has been received.
+func (c *Client) completeIfAnswered(ctx context.Context, manifestID string, now time.Time) error {
+	match := bson.M{
+		"expected_answers": bson.M{"$gt": 0},
+		"$expr":            bson.M{"$gte": []string{"$received_answers", "$expected_answers"}},
+	}
+	if _, err := c.transitionExecution(ctx, manifestID, ExecutionStatusCompleted, "all answers received", now, match, bson.M{"completed_at": now}); err != nil {
+		return fmt.Errorf("complete execution: %w", err)
+	}
+	return nil
+}
+
+// RecordDatapoint moves the datapoint's question from ANSWERED to EXTRACTED
+// and counts the datapoint against its execution. It returns false when the
+// question was already EXTRACTED, and a *TransitionError when the question
+// is not recorded or has no answer. The status and the count are written in
+// one transaction.
+func (c *Client) RecordDatapoint(ctx context.Context, evt events.ObjectiveDatapointV1Json) (bool, error) {
+	now := time.Now().UTC()
+	filter := bson.M{
+		"manifest_id": evt.Meta.ManifestId,
+		"question_id": evt.Meta.QuestionId,
+		"status":      bson.M{"$in": questionSources(QuestionStatusExtracted)},
+	}
+	counted, err := c.withTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		res, err := c.db.Collection(collQuestions).UpdateOne(sc, filter,
+			bson.M{"$set": bson.M{"status": QuestionStatusExtracted, "extracted_at": now}})
+		if err != nil {
+			return false, fmt.Errorf("mark question extracted: %w", err)
+		}
+		if res.ModifiedCount == 0 {
+			var doc struct {
+				Status string `bson:"status"`
+			}
+			err := c.db.Collection(collQuestions).FindOne(sc, bson.M{"manifest_id": evt.Meta.ManifestId, "question_id": evt.Meta.QuestionId}).Decode(&doc)
+			if err != nil && err != mongo.ErrNoDocuments {
+				return false, fmt.Errorf("find question: %w", err)
+			}
+			if doc.Status == QuestionStatusExtracted {
+				return false, nil
+			}
+			return false, &TransitionError{ManifestID: evt.Meta.ManifestId, QuestionID: evt.Meta.QuestionId, From: doc.Status, To: QuestionStatusExtracted}
+		}
+
+		if _, err := c.db.Collection(collExecutions).UpdateOne(sc,
+			bson.M{"manifest_id": evt.Meta.ManifestId},
+			bson.M{"$inc": bson.M{"datapoints": 1}, "$set": bson.M{"updated_at": now}}); err != nil {
+			return false, fmt.Errorf("count datapoint: %w", err)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return counted.(bool), nil
+}
+
+// FailExpiredExecutions marks in-flight executions whose deadline has passed
+// as FAILED and returns how many were updated.
+func (c *Client) FailExpiredExecutions(ctx context.Context, now time.Time) (int64, error) {
+	now = now.UTC()
+	reason := "deadline exceeded before all answers arrived"
+	filter := bson.M{
+		"status":      bson.M{"$in": executionSources(ExecutionStatusFailed)},
+		"deadline_at": bson.M{"$lt": now},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":         ExecutionStatusFailed,
+			"failure_reason": reason,
+			"completed_at":   now,
+			"updated_at":     now,
+		},
+		"$push": bson.M{"history": StatusChange{Status: ExecutionStatusFailed, At: now, Reason: reason}},
+	}
+	res, err := c.db.Collection(collExecutions).UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
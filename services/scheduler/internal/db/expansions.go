@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// collExpansions maps each fanned-out question_id back to the question it
+// was expanded from. Expanded ids are deterministic, so one document serves
+// every run of the same combination.
+const collExpansions = "question_expansions"
+
+// QuestionExpansion is one question asked with one model, as one persona, in
+// one language and location.
+type QuestionExpansion struct {
+	QuestionId       string `bson:"_id" json:"question_id"`
+	SourceQuestionId string `bson:"source_question_id" json:"source_question_id"`
+	ObjectiveId      string `bson:"objective_id" json:"objective_id"`
+	Model            string `bson:"model" json:"model"`
+	Persona          string `bson:"persona" json:"persona"`
+	Language         string `bson:"language" json:"language"`
+	Location         string `bson:"location" json:"location"`
+}
+
+// RecordQuestionExpansions upserts the expansion mapping for a run. Existing
+// entries are left untouched.
+func (c *Client) RecordQuestionExpansions(ctx context.Context, exps []QuestionExpansion) error {
+	if len(exps) == 0 {
+		return nil
+	}
+	now := time.Now().UTC()
+	models := make([]mongo.WriteModel, 0, len(exps))
+	for _, e := range exps {
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": e.QuestionId}).
+			SetUpdate(bson.M{"$setOnInsert": bson.M{
+				"source_question_id": e.SourceQuestionId,
+				"objective_id":       e.ObjectiveId,
+				"model":              e.Model,
+				"persona":            e.Persona,
+				"language":           e.Language,
+				"location":           e.Location,
+				"created_at":         now,
+			}}).
+			SetUpsert(true))
+	}
+	if _, err := c.db.Collection(collExpansions).BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false)); err != nil {
+		return fmt.Errorf("upsert question expansions: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const collLeases = "leases"
+
+// AcquireLease takes or renews the named lease for holder until now+ttl.
+// It succeeds when the lease is free, expired, or already held by holder,
+// and returns false when another holder owns an unexpired lease.
+func (c *Client) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now().UTC()
+	filter := bson.M{
+		"_id": name,
+		"$or": []bson.M{
+			{"holder": holder},
+			{"expires_at": bson.M{"$lt": now}},
+		},
+	}
+	update := bson.M{
+		"$set":         bson.M{"holder": holder, "expires_at": now.Add(ttl), "renewed_at": now},
+		"$setOnInsert": bson.M{"acquired_at": now},
+	}
+	_, err := c.db.Collection(collLeases).UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		// The filter did not match, so the upsert tried to insert a second
+		// document with the same _id: someone else holds the lease.
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ReleaseLease gives up the lease if holder still owns it, letting another
+// replica take over without waiting for it to expire.
+func (c *Client) ReleaseLease(ctx context.Context, name, holder string) error {
+	_, err := c.db.Collection(collLeases).UpdateOne(ctx,
+		bson.M{"_id": name, "holder": holder},
+		bson.M{"$set": bson.M{"expires_at": time.Unix(0, 0).UTC()}})
+	return err
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// An execution moves through these statuses:
+//
+//	PENDING     created by its manifest, or by an event that beat it
+//	PROCESSING  its first question event was recorded
+//	COMPLETED   every expected answer was received
+//	FAILED      its deadline passed, or every question not answered failed
+//
+// COMPLETED and FAILED are final. Each of its questions moves from PENDING to
+// ANSWERED when the answer is stored and on to EXTRACTED when its datapoint
+// arrives, or from PENDING to FAILED when the watchdog gives up on it. A late
+// answer still moves a FAILED question to ANSWERED.
+var (
+	executionTransitions = map[string][]string{
+		ExecutionStatusPending:    {ExecutionStatusProcessing, ExecutionStatusCompleted, ExecutionStatusFailed},
+		ExecutionStatusProcessing: {ExecutionStatusCompleted, ExecutionStatusFailed},
+	}
+	questionTransitions = map[string][]string{
+		QuestionStatusPending:  {QuestionStatusAnswered, QuestionStatusFailed},
+		QuestionStatusAnswered: {QuestionStatusExtracted},
+		QuestionStatusFailed:   {QuestionStatusAnswered},
+	}
+)
+
+// ErrIllegalTransition is wrapped by the errors of transitions the lifecycle
+// does not allow.
+var ErrIllegalTransition = errors.New("illegal transition")
+
+// TransitionError reports a rejected status change of an execution or of one
+// of its questions.
+type TransitionError struct {
+	ManifestID string
+	QuestionID string // empty for the execution itself
+	From, To   string // From is empty when the question is not recorded
+}
+
+func (e *TransitionError) Error() string {
+	from := e.From
+	if from == "" {
+		from = "unrecorded"
+	}
+	if e.QuestionID == "" {
+		return fmt.Sprintf("execution %s: %s -> %s: %v", e.ManifestID, from, e.To, ErrIllegalTransition)
+	}
+	return fmt.Sprintf("execution %s question %s: %s -> %s: %v", e.ManifestID, e.QuestionID, from, e.To, ErrIllegalTransition)
+}
+
+func (e *TransitionError) Unwrap() error { return ErrIllegalTransition }
+
+// StatusChange is one entry of an execution's history.
+type StatusChange struct {
+	Status string    `bson:"status" json:"status"`
+	At     time.Time `bson:"at" json:"at"`
+	Reason string    `bson:"reason,omitempty" json:"reason,omitempty"`
+}
+
+// CanTransitionExecution reports whether an execution may move from one
+// status to the other.
+func CanTransitionExecution(from, to string) bool {
+	return slices.Contains(executionTransitions[from], to)
+}
+
+// CanTransitionQuestion reports whether a question may move from one status
+// to the other.
+func CanTransitionQuestion(from, to string) bool {
+	return slices.Contains(questionTransitions[from], to)
+}
+
+// executionSources returns the statuses an execution may enter to from, for
+// use as a query filter.
+func executionSources(to string) []string {
+	return sources(executionTransitions, to)
+}
+
+// questionSources returns the statuses a question may enter to from.
+func questionSources(to string) []string {
+	return sources(questionTransitions, to)
+}
+
+func sources(transitions map[string][]string, to string) []string {
+	var out []string
+	for from, tos := range transitions {
+		if slices.Contains(tos, to) {
+			out = append(out, from)
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+// InFlight reports whether an execution in status may still change.
+func InFlight(status string) bool {
+	return len(executionTransitions[status]) > 0
+}
+
+// inFlightStatuses returns the statuses InFlight reports true for.
+func inFlightStatuses() []string {
+	out := make([]string, 0, len(executionTransitions))
+	for from := range executionTransitions {
+		out = append(out, from)
+	}
+	slices.Sort(out)
+	return out
+}
@@ -0,0 +1,116 @@
+package db
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCanTransition(t *testing.T) {
+	executions := []string{ExecutionStatusPending, ExecutionStatusProcessing, ExecutionStatusCompleted, ExecutionStatusFailed}
+	questions := []string{QuestionStatusPending, QuestionStatusAnswered, QuestionStatusExtracted, QuestionStatusFailed}
+	type move struct{ from, to string }
+	tests := []struct {
+		name     string
+		statuses []string
+		can      func(from, to string) bool
+		allowed  []move
+	}{
+		{
+			name:     "execution",
+			statuses: executions,
+			can:      CanTransitionExecution,
+			allowed: []move{
+				{ExecutionStatusPending, ExecutionStatusProcessing},
+				{ExecutionStatusPending, ExecutionStatusCompleted}, // every answer beat the questions
+				{ExecutionStatusPending, ExecutionStatusFailed},
+				{ExecutionStatusProcessing, ExecutionStatusCompleted},
+				{ExecutionStatusProcessing, ExecutionStatusFailed},
+			},
+		},
+		{
+			name:     "question",
+			statuses: questions,
+			can:      CanTransitionQuestion,
+			allowed: []move{
+				{QuestionStatusPending, QuestionStatusAnswered},
+				{QuestionStatusPending, QuestionStatusFailed},
+				{QuestionStatusAnswered, QuestionStatusExtracted},
+				{QuestionStatusFailed, QuestionStatusAnswered}, // a late answer
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed := make(map[move]bool, len(tt.allowed))
+			for _, m := range tt.allowed {
+				allowed[m] = true
+			}
+			// Every pair, including staying put and unknown statuses.
+			for _, from := range append(tt.statuses, "", "UNKNOWN") {
+				for _, to := range append(tt.statuses, "", "UNKNOWN") {
+					if got, want := tt.can(from, to), allowed[move{from, to}]; got != want {
+						t.Errorf("%s -> %s = %v, want %v", from, to, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSources(t *testing.T) {
+	tests := []struct {
+		name string
+		got  []string
+		want []string
+	}{
+		{"execution to PROCESSING", executionSources(ExecutionStatusProcessing), []string{ExecutionStatusPending}},
+		{"execution to COMPLETED", executionSources(ExecutionStatusCompleted), []string{ExecutionStatusPending, ExecutionStatusProcessing}},
+		{"execution to FAILED", executionSources(ExecutionStatusFailed), []string{ExecutionStatusPending, ExecutionStatusProcessing}},
+		{"execution to PENDING", executionSources(ExecutionStatusPending), nil},
+		{"question to ANSWERED", questionSources(QuestionStatusAnswered), []string{QuestionStatusFailed, QuestionStatusPending}},
+		{"question to EXTRACTED", questionSources(QuestionStatusExtracted), []string{QuestionStatusAnswered}},
+		{"question to FAILED", questionSources(QuestionStatusFailed), []string{QuestionStatusPending}},
+		{"in flight", inFlightStatuses(), []string{ExecutionStatusPending, ExecutionStatusProcessing}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Fatalf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+	for _, status := range []string{ExecutionStatusCompleted, ExecutionStatusFailed} {
+		if InFlight(status) {
+			t.Errorf("InFlight(%s) = true for a final status", status)
+		}
+	}
+}
+
+func TestTransitionError(t *testing.T) {
+	tests := []struct {
+		err  *TransitionError
+		want string
+	}{
+		{
+			err:  &TransitionError{ManifestID: "m-1", From: ExecutionStatusCompleted, To: ExecutionStatusFailed},
+			want: "execution m-1: COMPLETED -> FAILED: illegal transition",
+		},
+		{
+			err:  &TransitionError{ManifestID: "m-1", QuestionID: "q1", From: QuestionStatusPending, To: QuestionStatusExtracted},
+			want: "execution m-1 question q1: PENDING -> EXTRACTED: illegal transition",
+		},
+		{
+			err:  &TransitionError{ManifestID: "m-1", QuestionID: "q1", To: QuestionStatusExtracted},
+			want: "execution m-1 question q1: unrecorded -> EXTRACTED: illegal transition",
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("Error() = %q, want %q", got, tt.want)
+		}
+		if !errors.Is(tt.err, ErrIllegalTransition) {
+			t.Errorf("%v does not wrap ErrIllegalTransition", tt.err)
+		}
+	}
+}
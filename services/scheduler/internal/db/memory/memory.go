@@ -0,0 +1,677 @@
+// Package memory implements db.Store in process, for local runs without
+// MongoDB and for tests. It follows the semantics of the MongoDB store but
+// keeps nothing across restarts and expires nothing: dedupe records, spend
+// and sent outbox entries live until the process exits.
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"llm-your-business/schemas/events"
+	model "llm-your-business/services/go/models"
+	"llm-your-business/services/scheduler/internal/db"
+)
+
+// Store is a thread-safe in-memory db.Store. The zero value is not usable;
+// call New.
+type Store struct {
+	mu sync.Mutex
+
+	objectives     map[string]model.ObjectiveV1Json
+	questions      []model.QuestionV1Json
+	partnerBudgets map[string]model.Budget
+	expansions     map[string]db.QuestionExpansion
+	outbox         []db.OutboxMessage // pending and parked entries in publish order
+	executions     map[string]*db.Execution
+	execQuestions  map[questionKey]*question
+	answers        map[questionKey]bool
+	processed      map[string]*claim
+	spend          map[string]db.SpendRecord
+	leases         map[string]lease
+}
+
+var _ db.Store = (*Store)(nil)
+
+// questionKey identifies a question of one execution, like the unique
+// (manifest_id, question_id) index of the MongoDB collections.
+type questionKey struct {
+	manifestID string
+	questionID string
+}
+
+type question struct {
+	db.StalledQuestion
+	questionType events.QuestionType
+	status       string
+}
+
+type lease struct {
+	holder    string
+	expiresAt time.Time
+}
+
+func New() *Store {
+	return &Store{
+		objectives:     make(map[string]model.ObjectiveV1Json),
+		partnerBudgets: make(map[string]model.Budget),
+		expansions:     make(map[string]db.QuestionExpansion),
+		executions:     make(map[string]*db.Execution),
+		execQuestions:  make(map[questionKey]*question),
+		answers:        make(map[questionKey]bool),
+		processed:      make(map[string]*claim),
+		spend:          make(map[string]db.SpendRecord),
+		leases:         make(map[string]lease),
+	}
+}
+
+// Seed is the JSON document Store.Seed loads. Objectives are keyed by
+// document ID and partner budgets by partner_id.
+type Seed struct {
+	Objectives     map[string]model.ObjectiveV1Json `json:"objectives"`
+	Questions      []model.QuestionV1Json           `json:"questions"`
+	PartnerBudgets map[string]model.Budget          `json:"partner_budgets"`
+}
+
+// Seed adds the objectives, questions and partner budgets of a Seed document
+// read from r. Objectives and budgets with an existing ID are replaced.
+func (s *Store) Seed(r io.Reader) error {
+	var seed Seed
+	if err := json.NewDecoder(r).Decode(&seed); err != nil {
+		return fmt.Errorf("decode seed: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, obj := range seed.Objectives {
+		s.objectives[id] = obj
+	}
+	s.questions = append(s.questions, seed.Questions...)
+	for id, b := range seed.PartnerBudgets {
+		s.partnerBudgets[id] = b
+	}
+	return nil
+}
+
+// Ping always succeeds.
+func (s *Store) Ping(ctx context.Context) error { return nil }
+
+// cloneObjective copies obj through JSON, as the MongoDB store decodes it, so
+// callers cannot modify the stored objective.
+func cloneObjective(obj model.ObjectiveV1Json) (model.ObjectiveV1Json, error) {
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return model.ObjectiveV1Json{}, fmt.Errorf("encode objective: %w", err)
+	}
+	var out model.ObjectiveV1Json
+	if err := json.Unmarshal(b, &out); err != nil {
+		return model.ObjectiveV1Json{}, fmt.Errorf("decode objective: %w", err)
+	}
+	return out, nil
+}
+
+func (s *Store) FindActiveObjectives(ctx context.Context) (map[string]model.ObjectiveV1Json, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]model.ObjectiveV1Json, len(s.objectives))
+	for id, obj := range s.objectives {
+		if !obj.IsActive || obj.Paused {
+			continue
+		}
+		c, err := cloneObjective(obj)
+		if err != nil {
+			return nil, err
+		}
+		out[id] = c
+	}
+	return out, nil
+}
+
+func (s *Store) FindObjective(ctx context.Context, objectiveID string) (model.ObjectiveV1Json, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.objectives[objectiveID]
+	if !ok {
+		return model.ObjectiveV1Json{}, db.ErrNotFound
+	}
+	return cloneObjective(obj)
+}
+
+func (s *Store) FindQuestionsByObjective(ctx context.Context, objectiveID string) ([]model.QuestionV1Json, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []model.QuestionV1Json
+	for _, q := range s.questions {
+		if q.ObjectiveId == objectiveID {
+			out = append(out, q)
+		}
+	}
+	return out, nil
+}
+
+func (s *Store) SetObjectivePaused(ctx context.Context, objectiveID string, paused bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.objectives[objectiveID]
+	if !ok {
+		return db.ErrNotFound
+	}
+	if !paused && !obj.Paused {
+		return nil
+	}
+	now := time.Now().UTC()
+	obj.Paused = paused
+	obj.UpdatedAt = now
+	if !paused {
+		obj.ResumedAt = &now
+	}
+	s.objectives[objectiveID] = obj
+	return nil
+}
+
+func (s *Store) SetBudgetHold(ctx context.Context, objectiveID, reason string, since time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.objectives[objectiveID]
+	if !ok || (obj.BudgetHold != nil && obj.BudgetHold.Reason == reason) {
+		return nil
+	}
+	obj.BudgetHold = &model.BudgetHold{Reason: reason, Since: since.UTC()}
+	s.objectives[objectiveID] = obj
+	return nil
+}
+
+func (s *Store) RecordObjectiveRun(ctx context.Context, objectiveID string, run model.ObjectiveV1JsonRunsElem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recordRun(objectiveID, run)
+	return nil
+}
+
+// recordRun appends run to the objective, creating the objective when it does
+// not exist like the MongoDB upsert. Callers hold s.mu.
+func (s *Store) recordRun(objectiveID string, run model.ObjectiveV1JsonRunsElem) {
+	obj := s.objectives[objectiveID]
+	run.Timestamp = run.Timestamp.UTC()
+	run.ScheduledFor = run.ScheduledFor.UTC()
+	obj.Runs = append(obj.Runs, run)
+	obj.UpdatedAt = run.Timestamp
+	if !run.Skipped {
+		obj.BudgetHold = nil
+	}
+	s.objectives[objectiveID] = obj
+}
+
+func (s *Store) RecordQuestionExpansions(ctx context.Context, exps []db.QuestionExpansion) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range exps {
+		if _, ok := s.expansions[e.QuestionId]; !ok {
+			s.expansions[e.QuestionId] = e
+		}
+	}
+	return nil
+}
+
+func (s *Store) EnqueueObjectiveRun(ctx context.Context, objectiveID string, run model.ObjectiveV1JsonRunsElem, msgs []db.OutboxMessage) error {
+	now := time.Now().UTC()
+	tc := db.TraceContext(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, m := range msgs {
+		m.ID = primitive.NewObjectID()
+		m.Seq = i
+		m.Status = db.OutboxPending
+		m.CreatedAt = now
+		m.TraceContext = tc
+		s.outbox = append(s.outbox, m)
+	}
+	s.recordRun(objectiveID, run)
+	return nil
+}
+
+func (s *Store) PendingOutbox(ctx context.Context, limit int, skipKeys []string) ([]db.OutboxMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []db.OutboxMessage
+	for _, m := range s.outbox {
+		if len(out) >= limit {
+			break
+		}
+		if m.Status == db.OutboxPending && !slices.Contains(skipKeys, m.Key) {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+// MarkOutboxSent drops the message; sent entries are only kept in MongoDB
+// for troubleshooting.
+func (s *Store) MarkOutboxSent(ctx context.Context, id primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, m := range s.outbox {
+		if m.ID == id {
+			s.outbox = append(s.outbox[:i], s.outbox[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (s *Store) ParkOutbox(ctx context.Context, id primitive.ObjectID, status string, cause error) error {
+	s.updateOutbox(id, func(m *db.OutboxMessage) {
+		m.Status = status
+		m.LastError = cause.Error()
+		m.Attempts++
+	})
+	return nil
+}
+
+func (s *Store) ParkOutboxKey(ctx context.Context, key, status string, cause error) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for i := range s.outbox {
+		if m := &s.outbox[i]; m.Status == db.OutboxPending && m.Key == key {
+			m.Status = status
+			m.LastError = cause.Error()
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *Store) MarkOutboxFailed(ctx context.Context, id primitive.ObjectID, cause error, retryAt time.Time) error {
+	s.updateOutbox(id, func(m *db.OutboxMessage) {
+		m.LastError = cause.Error()
+		m.Attempts++
+		retryAt := retryAt.UTC()
+		m.NextAttemptAt = &retryAt
+	})
+	return nil
+}
+
+func (s *Store) updateOutbox(id primitive.ObjectID, fn func(*db.OutboxMessage)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.outbox {
+		if s.outbox[i].ID == id {
+			fn(&s.outbox[i])
+			return
+		}
+	}
+}
+
+func (s *Store) RecordManifest(ctx context.Context, evt events.ObjectiveManifestV1Json, deadline time.Time) error {
+	now := time.Now().UTC()
+	ids := make([]string, 0, len(evt.Data.Questions))
+	for _, q := range evt.Data.Questions {
+		ids = append(ids, q.QuestionId)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Questions and answers may arrive before their manifest; keep their
+	// counts and status.
+	exec := s.execution(evt.Meta.ManifestId, now, deadline, "manifest recorded")
+	exec.ExecutionId = evt.Meta.ExecutionId
+	exec.ObjectiveId = evt.Meta.ObjectiveId
+	exec.QuestionIds = ids
+	exec.ExpectedAnswers = len(ids)
+	exec.DeadlineAt = deadline.UTC()
+	exec.UpdatedAt = now
+	completeIfAnswered(exec, now)
+	return nil
+}
+
+// execution returns the manifest's execution, creating a PENDING one due at
+// deadline when there is none yet. Callers hold s.mu.
+func (s *Store) execution(manifestID string, now, deadline time.Time, reason string) *db.Execution {
+	exec, ok := s.executions[manifestID]
+	if !ok {
+		exec = &db.Execution{
+			ManifestId: manifestID,
+			Status:     db.ExecutionStatusPending,
+			History:    []db.StatusChange{{Status: db.ExecutionStatusPending, At: now, Reason: reason}},
+			DeadlineAt: deadline.UTC(),
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		}
+		s.executions[manifestID] = exec
+	}
+	return exec
+}
+
+// transition moves exec to status to when the lifecycle allows it and
+// reports whether it did.
+func transition(exec *db.Execution, to, reason string, now time.Time) bool {
+	if !db.CanTransitionExecution(exec.Status, to) {
+		return false
+	}
+	exec.Status = to
+	exec.History = append(exec.History, db.StatusChange{Status: to, At: now, Reason: reason})
+	exec.UpdatedAt = now
+	return true
+}
+
+// Execution returns a copy of the execution tracked for manifestID. It is
+// not part of db.Store; tests use it to follow an execution.
+func (s *Store) Execution(manifestID string) (db.Execution, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	exec, ok := s.executions[manifestID]
+	if !ok {
+		return db.Execution{}, false
+	}
+	out := *exec
+	out.QuestionIds = slices.Clone(exec.QuestionIds)
+	out.History = slices.Clone(exec.History)
+	return out, true
+}
+
+func completeIfAnswered(exec *db.Execution, now time.Time) {
+	if exec.ExpectedAnswers > 0 && exec.ReceivedAnswers >= exec.ExpectedAnswers &&
+		transition(exec, db.ExecutionStatusCompleted, "all answers received", now) {
+		exec.CompletedAt = &now
+	}
+}
+
+func (s *Store) SaveQuestion(ctx context.Context, evt events.ObjectiveExecutionQuestionV1Json, deadline time.Time) error {
+	now := time.Now().UTC()
+	meta, err := db.ToBSONM(evt.Meta)
+	if err != nil {
+		return fmt.Errorf("convert question meta: %w", err)
+	}
+	data, err := db.ToBSONM(evt.Data)
+	if err != nil {
+		return fmt.Errorf("convert question data: %w", err)
+	}
+	key := questionKey{evt.Meta.ManifestId, evt.Meta.QuestionId}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.execQuestions[key]; ok {
+		return nil
+	}
+	// The answer may have been stored before the question was recorded.
+	status := db.QuestionStatusPending
+	if s.answers[key] {
+		status = db.QuestionStatusAnswered
+	}
+	s.execQuestions[key] = &question{
+		StalledQuestion: db.StalledQuestion{
+			ManifestId:  evt.Meta.ManifestId,
+			ExecutionId: evt.Meta.ExecutionId,
+			QuestionId:  evt.Meta.QuestionId,
+			RunAttempt:  evt.Meta.RunAttempt,
+			AttemptedAt: now,
+			Meta:        meta,
+			Data:        data,
+		},
+		questionType: evt.Meta.QuestionType,
+		status:       status,
+	}
+	exec := s.execution(evt.Meta.ManifestId, now, deadline, "question before manifest")
+	if exec.ExecutionId == "" {
+		exec.ExecutionId = evt.Meta.ExecutionId
+		exec.ObjectiveId = evt.Meta.ObjectiveId
+	}
+	exec.QuestionsAsked++
+	exec.UpdatedAt = now
+	if transition(exec, db.ExecutionStatusProcessing, "first question recorded", now) {
+		exec.StartedAt = &now
+	}
+	return nil
+}
+
+func (s *Store) FindQuestionType(ctx context.Context, manifestID, questionID string) (events.QuestionType, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if q, ok := s.execQuestions[questionKey{manifestID, questionID}]; ok {
+		return q.questionType, nil
+	}
+	return "", nil
+}
+
+func (s *Store) SaveAnswer(ctx context.Context, evt events.ObjectiveExecutionAnswerV1Json, deadline time.Time) (bool, error) {
+	now := time.Now().UTC()
+	key := questionKey{evt.Meta.ManifestId, evt.Meta.QuestionId}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.answers[key] {
+		return false, nil
+	}
+	s.answers[key] = true
+	if q, ok := s.execQuestions[key]; ok && db.CanTransitionQuestion(q.status, db.QuestionStatusAnswered) {
+		q.status = db.QuestionStatusAnswered
+	}
+	exec := s.execution(evt.Meta.ManifestId, now, deadline, "answer before manifest")
+	if exec.ExecutionId == "" {
+		exec.ExecutionId = evt.Meta.ExecutionId
+		exec.ObjectiveId = evt.Meta.ObjectiveId
+	}
+	exec.ReceivedAnswers++
+	exec.UpdatedAt = now
+	completeIfAnswered(exec, now)
+	return true, nil
+}
+
+func (s *Store) RecordDatapoint(ctx context.Context, evt events.ObjectiveDatapointV1Json) (bool, error) {
+	now := time.Now().UTC()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var from string
+	q, ok := s.execQuestions[questionKey{evt.Meta.ManifestId, evt.Meta.QuestionId}]
+	if ok {
+		from = q.status
+	}
+	if from == db.QuestionStatusExtracted {
+		return false, nil
+	}
+	if !db.CanTransitionQuestion(from, db.QuestionStatusExtracted) {
+		return false, &db.TransitionError{ManifestID: evt.Meta.ManifestId, QuestionID: evt.Meta.QuestionId, From: from, To: db.QuestionStatusExtracted}
+	}
+	q.status = db.QuestionStatusExtracted
+	if exec, ok := s.executions[evt.Meta.ManifestId]; ok {
+		exec.Datapoints++
+		exec.UpdatedAt = now
+	}
+	return true, nil
+}
+
+func (s *Store) FailExpiredExecutions(ctx context.Context, now time.Time) (int64, error) {
+	now = now.UTC()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	reason := "deadline exceeded before all answers arrived"
+	for _, exec := range s.executions {
+		if !exec.DeadlineAt.Before(now) || !transition(exec, db.ExecutionStatusFailed, reason, now) {
+			continue
+		}
+		exec.FailureReason = reason
+		exec.CompletedAt = &now
+		n++
+	}
+	return n, nil
+}
+
+func (s *Store) FindStalledQuestions(ctx context.Context, cutoff time.Time, limit int) ([]db.StalledQuestion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []db.StalledQuestion
+	for key, q := range s.execQuestions {
+		if q.status != db.QuestionStatusPending || !q.AttemptedAt.Before(cutoff) {
+			continue
+		}
+		sq := q.StalledQuestion
+		if exec, ok := s.executions[key.manifestID]; ok {
+			sq.ExecutionStatus = exec.Status
+		}
+		sq.Meta = maps.Clone(q.Meta)
+		sq.Data = maps.Clone(q.Data)
+		out = append(out, sq)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].AttemptedAt.Before(out[j].AttemptedAt) })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (s *Store) RetryQuestion(ctx context.Context, sq db.StalledQuestion, msg db.OutboxMessage) (bool, error) {
+	now := time.Now().UTC()
+	msg.ID = primitive.NewObjectID()
+	msg.Status = db.OutboxPending
+	msg.CreatedAt = now
+	msg.TraceContext = db.TraceContext(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q, ok := s.execQuestions[questionKey{sq.ManifestId, sq.QuestionId}]
+	if !ok || q.status != db.QuestionStatusPending || q.RunAttempt != sq.RunAttempt {
+		return false, nil
+	}
+	q.RunAttempt = sq.RunAttempt + 1
+	q.Meta = maps.Clone(q.Meta)
+	q.Meta["run_attempt"] = q.RunAttempt
+	q.AttemptedAt = now
+	s.outbox = append(s.outbox, msg)
+	return true, nil
+}
+
+func (s *Store) FailQuestion(ctx context.Context, sq db.StalledQuestion, reason string) error {
+	now := time.Now().UTC()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q, ok := s.execQuestions[questionKey{sq.ManifestId, sq.QuestionId}]
+	if !ok || !db.CanTransitionQuestion(q.status, db.QuestionStatusFailed) {
+		return nil
+	}
+	q.status = db.QuestionStatusFailed
+	exec, ok := s.executions[sq.ManifestId]
+	if !ok {
+		return nil
+	}
+	exec.FailedQuestions++
+	exec.UpdatedAt = now
+	execReason := "questions unanswered after all attempts"
+	if exec.ExpectedAnswers > 0 && exec.ReceivedAnswers+exec.FailedQuestions >= exec.ExpectedAnswers &&
+		transition(exec, db.ExecutionStatusFailed, execReason, now) {
+		exec.FailureReason = execReason
+		exec.CompletedAt = &now
+	}
+	return nil
+}
+
+func processedKey(kind, executionID, questionID string, runAttempt int) string {
+	return fmt.Sprintf("%s:%s:%s:%d", kind, executionID, questionID, runAttempt)
+}
+
+// claim is a processed_events record.
+type claim struct {
+	done      bool
+	claimedAt time.Time
+}
+
+func (s *Store) ClaimEvent(ctx context.Context, kind, executionID, questionID string, runAttempt int, stale time.Duration) (db.ClaimState, error) {
+	now := time.Now().UTC()
+	key := processedKey(kind, executionID, questionID, runAttempt)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.processed[key]; ok {
+		if c.done {
+			return db.ClaimDone, nil
+		}
+		if !c.claimedAt.Before(now.Add(-stale)) {
+			return db.ClaimHeld, nil
+		}
+	}
+	s.processed[key] = &claim{claimedAt: now}
+	return db.ClaimAcquired, nil
+}
+
+func (s *Store) MarkProcessed(ctx context.Context, kind, executionID, questionID string, runAttempt int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.processed[processedKey(kind, executionID, questionID, runAttempt)] = &claim{done: true}
+	return nil
+}
+
+func (s *Store) ReleaseEvent(ctx context.Context, kind, executionID, questionID string, runAttempt int) error {
+	key := processedKey(kind, executionID, questionID, runAttempt)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.processed[key]; ok && !c.done {
+		delete(s.processed, key)
+	}
+	return nil
+}
+
+func (s *Store) RecordSpend(ctx context.Context, rec db.SpendRecord) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.spend[rec.ID]; ok {
+		return false, nil
+	}
+	s.spend[rec.ID] = rec
+	return true, nil
+}
+
+func (s *Store) ObjectiveSpendSince(ctx context.Context, objectiveID string, since time.Time) (db.Spend, error) {
+	return s.spendSince(since, func(rec db.SpendRecord) bool { return rec.ObjectiveId == objectiveID }), nil
+}
+
+func (s *Store) PartnerSpendSince(ctx context.Context, partnerID string, since time.Time) (db.Spend, error) {
+	return s.spendSince(since, func(rec db.SpendRecord) bool { return rec.PartnerId == partnerID }), nil
+}
+
+func (s *Store) spendSince(since time.Time, match func(db.SpendRecord) bool) db.Spend {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out db.Spend
+	for _, rec := range s.spend {
+		if rec.At.Before(since) || !match(rec) {
+			continue
+		}
+		out.Tokens += rec.InputTokens + rec.OutputTokens
+		out.CostUSD += rec.CostUSD
+	}
+	return out
+}
+
+func (s *Store) FindPartnerBudget(ctx context.Context, partnerID string) (*model.Budget, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.partnerBudgets[partnerID]
+	if !ok {
+		return nil, nil
+	}
+	return &b, nil
+}
+
+func (s *Store) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now().UTC()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if l, ok := s.leases[name]; ok && l.holder != holder && !l.expiresAt.Before(now) {
+		return false, nil
+	}
+	s.leases[name] = lease{holder: holder, expiresAt: now.Add(ttl)}
+	return true, nil
+}
+
+func (s *Store) ReleaseLease(ctx context.Context, name, holder string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if l, ok := s.leases[name]; ok && l.holder == holder {
+		delete(s.leases, name)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"llm-your-business/schemas/events"
+	"llm-your-business/services/scheduler/internal/db"
+	"llm-your-business/services/scheduler/internal/db/dbtest"
+)
+
+func TestStore(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T) dbtest.Store { return New() })
+}
+
+func TestRecordDatapointTransitions(t *testing.T) {
+	const manifestID, questionID = "m-1", "q1"
+	deadline := time.Now().Add(time.Hour)
+	ask := func(s *Store) error {
+		return s.SaveQuestion(context.Background(), events.ObjectiveExecutionQuestionV1Json{Meta: events.ObjectiveExecutionQuestionV1JsonMeta{
+			ManifestId: manifestID, ExecutionId: "e-1", QuestionId: questionID, RunAttempt: 1,
+		}}, deadline)
+	}
+	answer := func(s *Store) error {
+		_, err := s.SaveAnswer(context.Background(), events.ObjectiveExecutionAnswerV1Json{Meta: events.ObjectiveExecutionAnswerV1JsonMeta{
+			ManifestId: manifestID, ExecutionId: "e-1", QuestionId: questionID, RunAttempt: 1,
+		}}, deadline)
+		return err
+	}
+	fail := func(s *Store) error {
+		return s.FailQuestion(context.Background(), db.StalledQuestion{ManifestId: manifestID, QuestionId: questionID, RunAttempt: 1}, "no answer")
+	}
+	datapoint := events.ObjectiveDatapointV1Json{Meta: events.ObjectiveDatapointV1JsonMeta{ManifestId: manifestID, ExecutionId: "e-1", QuestionId: questionID}}
+	extract := func(s *Store) error {
+		_, err := s.RecordDatapoint(context.Background(), datapoint)
+		return err
+	}
+
+	tests := []struct {
+		name     string
+		setup    []func(*Store) error
+		want     bool
+		wantFrom string // the rejected transition's source; "-" when none is rejected
+	}{
+		{name: "unrecorded", wantFrom: ""},
+		{name: "pending", setup: []func(*Store) error{ask}, wantFrom: db.QuestionStatusPending},
+		{name: "failed", setup: []func(*Store) error{ask, fail}, wantFrom: db.QuestionStatusFailed},
+		{name: "answered", setup: []func(*Store) error{ask, answer}, want: true, wantFrom: "-"},
+		{name: "answered after failing", setup: []func(*Store) error{ask, fail, answer}, want: true, wantFrom: "-"},
+		{name: "answer before question", setup: []func(*Store) error{answer, ask}, want: true, wantFrom: "-"},
+		{name: "already extracted", setup: []func(*Store) error{ask, answer, extract}, wantFrom: "-"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			for _, step := range tt.setup {
+				if err := step(s); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := s.RecordDatapoint(context.Background(), datapoint)
+			if got != tt.want {
+				t.Errorf("counted = %v, want %v", got, tt.want)
+			}
+			var terr *db.TransitionError
+			if tt.wantFrom == "-" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if !errors.As(err, &terr) || !errors.Is(err, db.ErrIllegalTransition) {
+				t.Fatalf("err = %v, want a *db.TransitionError", err)
+			}
+			if terr.From != tt.wantFrom || terr.To != db.QuestionStatusExtracted || terr.QuestionID != questionID {
+				t.Fatalf("err = %+v, want %q -> %s", terr, tt.wantFrom, db.QuestionStatusExtracted)
+			}
+		})
+	}
+}
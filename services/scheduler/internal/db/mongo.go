@@ -26,6 +26,7 @@ func NewClient(ctx context.Context, uri, database string) (*Client, error) {
     opts.SetServerSelectionTimeout(5 * time.Second)
     opts.SetConnectTimeout(5 * time.Second)
     opts.SetSocketTimeout(10 * time.Second)
+    opts.SetMonitor((&commandTracer{}).monitor())
 
     c, err := mongo.Connect(ctx, opts)
     if err != nil {
@@ -35,15 +36,31 @@ func NewClient(ctx context.Context, uri, database string) (*Client, error) {
         _ = c.Disconnect(ctx)
         return nil, fmt.Errorf("mongo ping: %w", err)
     }
-    return &Client{client: c, db: c.Database(database)}, nil
+    client := &Client{client: c, db: c.Database(database)}
+    if err := client.ensureExecutionIndexes(ctx); err != nil {
+        _ = c.Disconnect(ctx)
+        return nil, err
+    }
+    if err := client.ensureOutboxIndexes(ctx); err != nil {
+        _ = c.Disconnect(ctx)
+        return nil, err
+    }
+    if err := client.ensureSpendIndexes(ctx); err != nil {
+        _ = c.Disconnect(ctx)
+        return nil, err
+    }
+    return client, nil
 }
 
 func (c *Client) Disconnect(ctx context.Context) error { return c.client.Disconnect(ctx) }
 
-// FindActiveObjectives returns active objectives keyed by document ID.
+// Ping checks that the deployment is reachable.
+func (c *Client) Ping(ctx context.Context) error { return c.client.Ping(ctx, nil) }
+
+// FindActiveObjectives returns active, unpaused objectives keyed by document ID.
 // Values are decoded into the generated model.ObjectiveV1Json type.
 func (c *Client) FindActiveObjectives(ctx context.Context) (map[string]model.ObjectiveV1Json, error) {
-    filter := bson.M{"$or": []bson.M{{"is_active": true}, {"isActive": true}}}
+    filter := bson.M{"$or": []bson.M{{"is_active": true}, {"isActive": true}}, "paused": bson.M{"$ne": true}}
     cur, err := c.db.Collection("objectives").Find(ctx, filter)
     if err != nil {
         return nil, err
@@ -112,16 +129,35 @@ func (c *Client) FindQuestionsByObjective(ctx context.Context, objectiveID strin
 }
 
 // RecordObjectiveRun appends a run entry for an objective and updates updated_at.
-func (c *Client) RecordObjectiveRun(ctx context.Context, objectiveID string, when time.Time, manifestID string) error {
+// Runs that were not skipped clear the objective's budget_hold.
+func (c *Client) RecordObjectiveRun(ctx context.Context, objectiveID string, run model.ObjectiveV1JsonRunsElem) error {
     var filter bson.M
     if oid, err := primitive.ObjectIDFromHex(objectiveID); err == nil {
         filter = bson.M{"_id": oid}
     } else {
         filter = bson.M{"_id": objectiveID}
     }
+    entry := bson.M{"timestamp": run.Timestamp.UTC(), "manifest_id": run.ManifestId}
+    if !run.ScheduledFor.IsZero() {
+        entry["scheduled_for"] = run.ScheduledFor.UTC()
+    }
+    if run.Backfill {
+        entry["backfill"] = true
+    }
+    if run.Manual {
+        entry["manual"] = true
+    }
+    if run.Skipped {
+        entry["skipped"] = true
+        entry["skip_reason"] = run.SkipReason
+    }
     update := bson.M{
-        "$push": bson.M{"runs": bson.M{"timestamp": when.UTC(), "manifest_id": manifestID}},
-        "$set":  bson.M{"updated_at": when.UTC()},
+        "$push": bson.M{"runs": entry},
+        "$set":  bson.M{"updated_at": run.Timestamp.UTC()},
+    }
+    if !run.Skipped {
+        // A run that went ahead ends any budget hold.
+        update["$unset"] = bson.M{"budget_hold": ""}
     }
     opts := options.Update().SetUpsert(true)
     _, err := c.db.Collection("objectives").UpdateOne(ctx, filter, update, opts)
@@ -0,0 +1,56 @@
+package db_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"llm-your-business/services/scheduler/internal/db"
+	"llm-your-business/services/scheduler/internal/db/dbtest"
+)
+
+// TestClient runs the store suite against MONGODB_TEST_URI, which must point
+// at a replica set (transactions need one). Each subtest gets its own
+// database, dropped afterwards.
+func TestClient(t *testing.T) {
+	uri := os.Getenv("MONGODB_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGODB_TEST_URI not set")
+	}
+	ctx := context.Background()
+	raw, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = raw.Disconnect(ctx) })
+
+	dbtest.Run(t, func(t *testing.T) dbtest.Store {
+		name := fmt.Sprintf("scheduler_test_%d", time.Now().UnixNano())
+		c, err := db.NewClient(ctx, uri, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			_ = raw.Database(name).Drop(ctx)
+			_ = c.Disconnect(ctx)
+		})
+		return mongoStore{Client: c, executions: raw.Database(name).Collection("objective_executions")}
+	})
+}
+
+type mongoStore struct {
+	*db.Client
+	executions *mongo.Collection
+}
+
+func (s mongoStore) Execution(manifestID string) (db.Execution, bool) {
+	var exec db.Execution
+	err := s.executions.FindOne(context.Background(), bson.M{"manifest_id": manifestID}).Decode(&exec)
+	return exec, err == nil
+}
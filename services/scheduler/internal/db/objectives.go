@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	model "llm-your-business/services/go/models"
+)
+
+// ErrNotFound is returned when a looked-up document does not exist.
+var ErrNotFound = errors.New("not found")
+
+// objectiveFilter matches an objective by ObjectId hex or string _id.
+func objectiveFilter(objectiveID string) bson.M {
+	if oid, err := primitive.ObjectIDFromHex(objectiveID); err == nil {
+		return bson.M{"_id": oid}
+	}
+	return bson.M{"_id": objectiveID}
+}
+
+// FindObjective returns one objective regardless of whether it is active or
+// paused, or ErrNotFound.
+func (c *Client) FindObjective(ctx context.Context, objectiveID string) (model.ObjectiveV1Json, error) {
+	var raw bson.M
+	err := c.db.Collection("objectives").FindOne(ctx, objectiveFilter(objectiveID)).Decode(&raw)
+	if err == mongo.ErrNoDocuments {
+		return model.ObjectiveV1Json{}, ErrNotFound
+	}
+	if err != nil {
+		return model.ObjectiveV1Json{}, err
+	}
+	// Same JSON round-trip as FindActiveObjectives.
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return model.ObjectiveV1Json{}, fmt.Errorf("encode objective: %w", err)
+	}
+	var obj model.ObjectiveV1Json
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return model.ObjectiveV1Json{}, fmt.Errorf("decode objective: %w", err)
+	}
+	return obj, nil
+}
+
+// SetObjectivePaused pauses or resumes scheduled runs of an objective.
+// Resuming a paused objective records resumed_at so the slots skipped while
+// paused are not caught up. Returns ErrNotFound when the objective does not
+// exist.
+func (c *Client) SetObjectivePaused(ctx context.Context, objectiveID string, paused bool) error {
+	now := time.Now().UTC()
+	filter := objectiveFilter(objectiveID)
+	set := bson.M{"paused": paused, "updated_at": now}
+	if !paused {
+		filter["paused"] = true
+		set["resumed_at"] = now
+	}
+	res, err := c.db.Collection("objectives").UpdateOne(ctx, filter, bson.M{"$set": set})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount > 0 {
+		return nil
+	}
+	// Either missing or, when resuming, not paused in the first place.
+	n, err := c.db.Collection("objectives").CountDocuments(ctx, objectiveFilter(objectiveID))
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	model "llm-your-business/services/go/models"
+)
+
+const (
+	collOutbox = "outbox"
+	// Sent entries are kept this long for troubleshooting, then expire.
+	outboxSentRetention = 7 * 24 * time.Hour
+)
+
+// Outbox entry status values.
+const (
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+	OutboxInvalid = "invalid" // refused by schema validation; never published
+	OutboxFailed  = "failed"  // publishing kept failing; never published
+)
+
+// OutboxMessage is a Kafka message waiting to be published by the relay.
+type OutboxMessage struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	Topic     string             `bson:"topic"`
+	Key       string             `bson:"key"`
+	Payload   string             `bson:"payload"`
+	Seq       int                `bson:"seq"` // publish order within one enqueue
+	Status    string             `bson:"status"`
+	Attempts  int                `bson:"attempts"`
+	LastError string             `bson:"last_error,omitempty"`
+	CreatedAt time.Time          `bson:"created_at"`
+	SentAt    *time.Time         `bson:"sent_at,omitempty"`
+	// After a failed attempt, the entry is not tried again before this.
+	NextAttemptAt *time.Time `bson:"next_attempt_at,omitempty"`
+	// W3C trace context of the span that enqueued the message; the relay
+	// publishes under it so the trace continues across the outbox.
+	TraceContext map[string]string `bson:"trace_context,omitempty"`
+}
+
+func (c *Client) ensureOutboxIndexes(ctx context.Context) error {
+	_, err := c.db.Collection(collOutbox).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}, {Key: "seq", Value: 1}}},
+		{Keys: bson.D{{Key: "sent_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(int32(outboxSentRetention / time.Second))},
+	})
+	if err != nil {
+		return fmt.Errorf("outbox indexes: %w", err)
+	}
+	return nil
+}
+
+// EnqueueObjectiveRun writes the run's outgoing messages to the outbox and
+// appends the run entry to the objective in a single transaction, so either
+// the whole run is recorded or none of it is. Requires a replica set.
+func (c *Client) EnqueueObjectiveRun(ctx context.Context, objectiveID string, run model.ObjectiveV1JsonRunsElem, msgs []OutboxMessage) error {
+	now := time.Now().UTC()
+	tc := TraceContext(ctx)
+	docs := make([]interface{}, 0, len(msgs))
+	for i, m := range msgs {
+		m.Seq = i
+		m.Status = OutboxPending
+		m.CreatedAt = now
+		m.TraceContext = tc
+		docs = append(docs, m)
+	}
+
+	sess, err := c.client.StartSession()
+	if err != nil {
+		return fmt.Errorf("start session: %w", err)
+	}
+	defer sess.EndSession(ctx)
+
+	_, err = sess.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		if len(docs) > 0 {
+			if _, err := c.db.Collection(collOutbox).InsertMany(sc, docs); err != nil {
+				return nil, fmt.Errorf("insert outbox: %w", err)
+			}
+		}
+		if err := c.RecordObjectiveRun(sc, objectiveID, run); err != nil {
+			return nil, fmt.Errorf("record run: %w", err)
+		}
+		return nil, nil
+	})
+	return err
+}
+
+// PendingOutbox returns up to limit unsent messages in publish order,
+// leaving out those keyed by one of skipKeys.
+func (c *Client) PendingOutbox(ctx context.Context, limit int, skipKeys []string) ([]OutboxMessage, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "seq", Value: 1}}).
+		SetLimit(int64(limit))
+	filter := bson.M{"status": OutboxPending}
+	if len(skipKeys) > 0 {
+		filter["key"] = bson.M{"$nin": skipKeys}
+	}
+	cur, err := c.db.Collection(collOutbox).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	var out []OutboxMessage
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MarkOutboxSent flags a message as published.
+func (c *Client) MarkOutboxSent(ctx context.Context, id primitive.ObjectID) error {
+	now := time.Now().UTC()
+	_, err := c.db.Collection(collOutbox).UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"status": OutboxSent, "sent_at": now}, "$inc": bson.M{"attempts": 1}})
+	return err
+}
+
+// ParkOutbox takes a message that cannot be published out of the pending set
+// with status OutboxInvalid or OutboxFailed, so it does not block the messages
+// behind it. It is kept with cause for inspection.
+func (c *Client) ParkOutbox(ctx context.Context, id primitive.ObjectID, status string, cause error) error {
+	_, err := c.db.Collection(collOutbox).UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"status": status, "last_error": cause.Error()}, "$inc": bson.M{"attempts": 1}})
+	return err
+}
+
+// ParkOutboxKey parks every pending message with key, such as the questions
+// of an execution whose manifest was parked, and returns how many it parked.
+func (c *Client) ParkOutboxKey(ctx context.Context, key, status string, cause error) (int64, error) {
+	res, err := c.db.Collection(collOutbox).UpdateMany(ctx,
+		bson.M{"status": OutboxPending, "key": key},
+		bson.M{"$set": bson.M{"status": status, "last_error": cause.Error()}})
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+// MarkOutboxFailed records a failed publish attempt; the message stays
+// pending and is tried again from retryAt.
+func (c *Client) MarkOutboxFailed(ctx context.Context, id primitive.ObjectID, cause error, retryAt time.Time) error {
+	_, err := c.db.Collection(collOutbox).UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"last_error": cause.Error(), "next_attempt_at": retryAt.UTC()}, "$inc": bson.M{"attempts": 1}})
+	return err
+}
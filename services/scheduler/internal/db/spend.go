@@ -0,0 +1,136 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	model "llm-your-business/services/go/models"
+)
+
+const (
+	collSpend          = "spend"
+	collPartnerBudgets = "partner_budgets"
+	// Spend records only matter for the current day and month.
+	spendRetention = 62 * 24 * time.Hour
+)
+
+// SpendRecord is the cost of one answer. Its _id is the answer's dedupe key,
+// so a redelivered answer is not charged twice.
+type SpendRecord struct {
+	ID           string    `bson:"_id"`
+	ObjectiveId  string    `bson:"objective_id"`
+	PartnerId    string    `bson:"partner_id,omitempty"`
+	Model        string    `bson:"model"`
+	InputTokens  int64     `bson:"input_tokens"`
+	OutputTokens int64     `bson:"output_tokens"`
+	CostUSD      float64   `bson:"cost_usd"`
+	At           time.Time `bson:"at"`
+}
+
+// Spend totals SpendRecords.
+type Spend struct {
+	Tokens  int64   `bson:"tokens"`
+	CostUSD float64 `bson:"cost_usd"`
+}
+
+func (c *Client) ensureSpendIndexes(ctx context.Context) error {
+	_, err := c.db.Collection(collSpend).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "objective_id", Value: 1}, {Key: "at", Value: 1}}},
+		{Keys: bson.D{{Key: "partner_id", Value: 1}, {Key: "at", Value: 1}}},
+		{Keys: bson.D{{Key: "at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(int32(spendRetention / time.Second))},
+	})
+	if err != nil {
+		return fmt.Errorf("spend indexes: %w", err)
+	}
+	return nil
+}
+
+// SpendKey identifies the spend of one answer attempt.
+func SpendKey(executionID, questionID string, runAttempt int) string {
+	return fmt.Sprintf("%s:%s:%d", executionID, questionID, runAttempt)
+}
+
+// RecordSpend stores rec unless a record with its ID exists. Returns whether
+// it was inserted.
+func (c *Client) RecordSpend(ctx context.Context, rec SpendRecord) (bool, error) {
+	_, err := c.db.Collection(collSpend).InsertOne(ctx, rec)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ObjectiveSpendSince totals the objective's spend recorded at or after since.
+func (c *Client) ObjectiveSpendSince(ctx context.Context, objectiveID string, since time.Time) (Spend, error) {
+	return c.spendSince(ctx, bson.M{"objective_id": objectiveID, "at": bson.M{"$gte": since}})
+}
+
+// PartnerSpendSince totals the spend of all of the partner's objectives
+// recorded at or after since.
+func (c *Client) PartnerSpendSince(ctx context.Context, partnerID string, since time.Time) (Spend, error) {
+	return c.spendSince(ctx, bson.M{"partner_id": partnerID, "at": bson.M{"$gte": since}})
+}
+
+func (c *Client) spendSince(ctx context.Context, match bson.M) (Spend, error) {
+	cur, err := c.db.Collection(collSpend).Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      nil,
+			"tokens":   bson.M{"$sum": bson.M{"$add": bson.A{"$input_tokens", "$output_tokens"}}},
+			"cost_usd": bson.M{"$sum": "$cost_usd"},
+		}}},
+	})
+	if err != nil {
+		return Spend{}, err
+	}
+	defer cur.Close(ctx)
+	var out Spend
+	if cur.Next(ctx) {
+		if err := cur.Decode(&out); err != nil {
+			return Spend{}, err
+		}
+	}
+	return out, cur.Err()
+}
+
+// FindPartnerBudget returns the partner's budget from partner_budgets, keyed
+// by partner_id, or nil when it has none.
+func (c *Client) FindPartnerBudget(ctx context.Context, partnerID string) (*model.Budget, error) {
+	var doc struct {
+		DailyTokens    int64   `bson:"daily_tokens"`
+		MonthlyTokens  int64   `bson:"monthly_tokens"`
+		DailyCostUSD   float64 `bson:"daily_cost_usd"`
+		MonthlyCostUSD float64 `bson:"monthly_cost_usd"`
+	}
+	err := c.db.Collection(collPartnerBudgets).FindOne(ctx, bson.M{"_id": partnerID}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &model.Budget{
+		DailyTokens:    doc.DailyTokens,
+		MonthlyTokens:  doc.MonthlyTokens,
+		DailyCostUSD:   doc.DailyCostUSD,
+		MonthlyCostUSD: doc.MonthlyCostUSD,
+	}, nil
+}
+
+// SetBudgetHold records why the objective's scheduled runs are deferred. The
+// hold is cleared when a run is next recorded.
+func (c *Client) SetBudgetHold(ctx context.Context, objectiveID, reason string, since time.Time) error {
+	// Keep the original since while the reason stays the same.
+	_, err := c.db.Collection("objectives").UpdateOne(ctx,
+		bson.M{"$and": bson.A{objectiveFilter(objectiveID), bson.M{"budget_hold.reason": bson.M{"$ne": reason}}}},
+		bson.M{"$set": bson.M{"budget_hold": bson.M{"reason": reason, "since": since.UTC()}}})
+	return err
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"llm-your-business/schemas/events"
+	model "llm-your-business/services/go/models"
+)
+
+// Store is the scheduler's storage. *Client implements it on MongoDB and
+// package memory implements it in process. Components depend on Store, or on
+// the narrower interfaces it is made of, rather than on *Client.
+type Store interface {
+	Objectives
+	Runs
+	Executions
+	Dedupe
+	Spending
+	Leases
+	// Ping checks that the storage is reachable.
+	Ping(ctx context.Context) error
+}
+
+// Objectives reads objectives and their questions and updates the fields the
+// scheduler owns. Lookups of a missing objective return ErrNotFound.
+type Objectives interface {
+	FindActiveObjectives(ctx context.Context) (map[string]model.ObjectiveV1Json, error)
+	FindObjective(ctx context.Context, objectiveID string) (model.ObjectiveV1Json, error)
+	FindQuestionsByObjective(ctx context.Context, objectiveID string) ([]model.QuestionV1Json, error)
+	SetObjectivePaused(ctx context.Context, objectiveID string, paused bool) error
+	SetBudgetHold(ctx context.Context, objectiveID, reason string, since time.Time) error
+}
+
+// Runs records objective runs and the outbox their messages wait in until
+// the relay publishes them.
+type Runs interface {
+	RecordObjectiveRun(ctx context.Context, objectiveID string, run model.ObjectiveV1JsonRunsElem) error
+	RecordQuestionExpansions(ctx context.Context, exps []QuestionExpansion) error
+	EnqueueObjectiveRun(ctx context.Context, objectiveID string, run model.ObjectiveV1JsonRunsElem, msgs []OutboxMessage) error
+	PendingOutbox(ctx context.Context, limit int, skipKeys []string) ([]OutboxMessage, error)
+	MarkOutboxSent(ctx context.Context, id primitive.ObjectID) error
+	MarkOutboxFailed(ctx context.Context, id primitive.ObjectID, cause error, retryAt time.Time) error
+	ParkOutbox(ctx context.Context, id primitive.ObjectID, status string, cause error) error
+	ParkOutboxKey(ctx context.Context, key, status string, cause error) (int64, error)
+}
+
+// Executions tracks executions and their questions through the lifecycle
+// in lifecycle.go, driven by manifest, question, answer and datapoint events.
+type Executions interface {
+	RecordManifest(ctx context.Context, evt events.ObjectiveManifestV1Json, deadline time.Time) error
+	SaveQuestion(ctx context.Context, evt events.ObjectiveExecutionQuestionV1Json, deadline time.Time) error
+	FindQuestionType(ctx context.Context, manifestID, questionID string) (events.QuestionType, error)
+	SaveAnswer(ctx context.Context, evt events.ObjectiveExecutionAnswerV1Json, deadline time.Time) (bool, error)
+	RecordDatapoint(ctx context.Context, evt events.ObjectiveDatapointV1Json) (bool, error)
+	FailExpiredExecutions(ctx context.Context, now time.Time) (int64, error)
+	FindStalledQuestions(ctx context.Context, cutoff time.Time, limit int) ([]StalledQuestion, error)
+	RetryQuestion(ctx context.Context, q StalledQuestion, msg OutboxMessage) (bool, error)
+	FailQuestion(ctx context.Context, q StalledQuestion, reason string) error
+}
+
+// Dedupe claims events before they are handled and remembers which were.
+type Dedupe interface {
+	ClaimEvent(ctx context.Context, kind, executionID, questionID string, runAttempt int, stale time.Duration) (ClaimState, error)
+	MarkProcessed(ctx context.Context, kind, executionID, questionID string, runAttempt int) error
+	ReleaseEvent(ctx context.Context, kind, executionID, questionID string, runAttempt int) error
+}
+
+// Spending records answer spend and reads budgets.
+type Spending interface {
+	RecordSpend(ctx context.Context, rec SpendRecord) (bool, error)
+	ObjectiveSpendSince(ctx context.Context, objectiveID string, since time.Time) (Spend, error)
+	PartnerSpendSince(ctx context.Context, partnerID string, since time.Time) (Spend, error)
+	FindPartnerBudget(ctx context.Context, partnerID string) (*model.Budget, error)
+}
+
+// Leases backs leader election.
+type Leases interface {
+	AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error)
+	ReleaseLease(ctx context.Context, name, holder string) error
+}
+
+var _ Store = (*Client)(nil)
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("llm-your-business/services/scheduler/internal/db")
+
+// commandTracer records a client span per MongoDB command. The driver reports
+// a command's start and end separately, so open spans are keyed by request.
+type commandTracer struct {
+	spans sync.Map // spanKey -> trace.Span
+}
+
+type spanKey struct {
+	conn string
+	id   int64
+}
+
+func (t *commandTracer) monitor() *event.CommandMonitor {
+	return &event.CommandMonitor{Started: t.started, Succeeded: t.succeeded, Failed: t.failed}
+}
+
+func (t *commandTracer) started(ctx context.Context, e *event.CommandStartedEvent) {
+	name := e.CommandName
+	attrs := []trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemMongoDB,
+			semconv.DBNamespace(e.DatabaseName),
+			semconv.DBOperationName(e.CommandName),
+		),
+	}
+	// The first element of a command document names the collection for
+	// collection-level commands (find, insert, update, aggregate, ...).
+	if coll, ok := e.Command.Lookup(e.CommandName).StringValueOK(); ok {
+		name = e.CommandName + " " + coll
+		attrs = append(attrs, trace.WithAttributes(semconv.DBCollectionName(coll)))
+	}
+	_, span := tracer.Start(ctx, name, attrs...)
+	t.spans.Store(spanKey{e.ConnectionID, e.RequestID}, span)
+}
+
+func (t *commandTracer) succeeded(_ context.Context, e *event.CommandSucceededEvent) {
+	if span, ok := t.spans.LoadAndDelete(spanKey{e.ConnectionID, e.RequestID}); ok {
+		span.(trace.Span).End()
+	}
+}
+
+func (t *commandTracer) failed(_ context.Context, e *event.CommandFailedEvent) {
+	if span, ok := t.spans.LoadAndDelete(spanKey{e.ConnectionID, e.RequestID}); ok {
+		s := span.(trace.Span)
+		s.SetStatus(codes.Error, fmt.Sprint(e.Failure))
+		s.End()
+	}
+}
+
+// TraceContext returns the W3C trace context of ctx's span, for messages that
+// are published later by the outbox relay. It is nil when there is no span.
+func TraceContext(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return nil
+	}
+	return carrier
+}
@@ -0,0 +1,137 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// StalledQuestion is a pending question that has gone unanswered since its
+// last attempt was emitted.
+type StalledQuestion struct {
+	ManifestId      string    `bson:"manifest_id"`
+	ExecutionId     string    `bson:"execution_id"`
+	QuestionId      string    `bson:"question_id"`
+	RunAttempt      int       `bson:"run_attempt"`
+	AttemptedAt     time.Time `bson:"attempted_at"`
+	ExecutionStatus string    `bson:"execution_status"` // status of the question's execution when it was found
+	Meta            bson.M    `bson:"meta"`             // question event meta as stored by SaveQuestion
+	Data            bson.M    `bson:"data"`
+}
+
+// FindStalledQuestions returns up to limit pending questions whose latest
+// attempt was emitted before cutoff, oldest first. Answered questions are no
+// longer pending, so only the (status, attempted_at) index is scanned and the
+// execution is looked up for the returned page alone. Questions of executions
+// that are no longer in flight are returned too, with ExecutionStatus set, so
+// the caller can close them instead of letting them fill every page.
+func (c *Client) FindStalledQuestions(ctx context.Context, cutoff time.Time, limit int) ([]StalledQuestion, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"status":       QuestionStatusPending,
+			"attempted_at": bson.M{"$lt": cutoff.UTC()},
+		}}},
+		{{Key: "$sort", Value: bson.M{"attempted_at": 1}}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         collExecutions,
+			"localField":   "manifest_id",
+			"foreignField": "manifest_id",
+			"as":           "execution",
+		}}},
+		{{Key: "$set", Value: bson.M{"execution_status": bson.M{"$first": "$execution.status"}}}},
+		{{Key: "$project", Value: bson.M{"execution": 0}}},
+	}
+	cur, err := c.db.Collection(collQuestions).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	var out []StalledQuestion
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RetryQuestion records the next attempt of q and enqueues its re-emitted
+// event in one transaction. It returns false, enqueuing nothing, when q has
+// moved on since it was read (answered, failed or retried elsewhere).
+func (c *Client) RetryQuestion(ctx context.Context, q StalledQuestion, msg OutboxMessage) (bool, error) {
+	now := time.Now().UTC()
+	msg.Status = OutboxPending
+	msg.CreatedAt = now
+	msg.TraceContext = TraceContext(ctx)
+
+	sess, err := c.client.StartSession()
+	if err != nil {
+		return false, fmt.Errorf("start session: %w", err)
+	}
+	defer sess.EndSession(ctx)
+
+	res, err := sess.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		filter := bson.M{
+			"manifest_id": q.ManifestId,
+			"question_id": q.QuestionId,
+			"status":      QuestionStatusPending,
+			"run_attempt": q.RunAttempt,
+		}
+		update := bson.M{"$set": bson.M{
+			"run_attempt":      q.RunAttempt + 1,
+			"meta.run_attempt": q.RunAttempt + 1,
+			"attempted_at":     now,
+		}}
+		upd, err := c.db.Collection(collQuestions).UpdateOne(sc, filter, update)
+		if err != nil {
+			return false, fmt.Errorf("bump attempt: %w", err)
+		}
+		if upd.ModifiedCount == 0 {
+			return false, nil
+		}
+		if _, err := c.db.Collection(collOutbox).InsertOne(sc, msg); err != nil {
+			return false, fmt.Errorf("insert outbox: %w", err)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return res.(bool), nil
+}
+
+// FailQuestion gives up on q after its last attempt. The execution is marked
+// FAILED once every question is either answered or failed.
+func (c *Client) FailQuestion(ctx context.Context, q StalledQuestion, reason string) error {
+	now := time.Now().UTC()
+	res, err := c.db.Collection(collQuestions).UpdateOne(ctx,
+		bson.M{"manifest_id": q.ManifestId, "question_id": q.QuestionId, "status": bson.M{"$in": questionSources(QuestionStatusFailed)}},
+		bson.M{"$set": bson.M{"status": QuestionStatusFailed, "failure_reason": reason, "failed_at": now}})
+	if err != nil {
+		return fmt.Errorf("fail question: %w", err)
+	}
+	if res.ModifiedCount == 0 {
+		return nil
+	}
+
+	if _, err := c.db.Collection(collExecutions).UpdateOne(ctx,
+		bson.M{"manifest_id": q.ManifestId},
+		bson.M{"$inc": bson.M{"failed_questions": 1}, "$set": bson.M{"updated_at": now}}); err != nil {
+		return fmt.Errorf("count failed question: %w", err)
+	}
+	match := bson.M{
+		"expected_answers": bson.M{"$gt": 0},
+		"$expr": bson.M{"$gte": []interface{}{
+			bson.M{"$add": []string{"$received_answers", "$failed_questions"}},
+			"$expected_answers",
+		}},
+	}
+	execReason := "questions unanswered after all attempts"
+	set := bson.M{"failure_reason": execReason, "completed_at": now}
+	if _, err := c.transitionExecution(ctx, q.ManifestId, ExecutionStatusFailed, execReason, now, match, set); err != nil {
+		return fmt.Errorf("fail execution: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+// Package decode turns Kafka payloads into the scheduler's in-memory event
+// types, whichever meta.schema_version they were produced with.
+//
+// Each topic has a Decoder for the type of its current schema version.
+// Payloads of older versions are decoded into their own generated type and
+// converted by an upcaster. Producers on either version can then run side by
+// side while a schema change rolls out.
+package decode
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Decoder decodes the payloads of one topic into T, the type of the topic's
+// current schema version.
+type Decoder[T any] struct {
+	topic    string
+	current  int
+	versions map[int]func([]byte) (T, error)
+	logAttrs func(T) []any
+}
+
+// New returns a decoder for topic that decodes payloads of schema version
+// current straight into T. Register older versions with Upcast.
+func New[T any](topic string, current int) *Decoder[T] {
+	d := &Decoder[T]{topic: topic, current: current, versions: make(map[int]func([]byte) (T, error))}
+	d.versions[current] = func(payload []byte) (T, error) {
+		var evt T
+		err := json.Unmarshal(payload, &evt)
+		return evt, err
+	}
+	return d
+}
+
+// Upcast registers how payloads of an older schema version are read: they are
+// decoded into Old and converted to T by up. To skip versions, up can call
+// the converter of the next version itself. Returns d for chaining.
+func Upcast[Old, T any](d *Decoder[T], version int, up func(Old) (T, error)) *Decoder[T] {
+	if version == d.current {
+		panic(fmt.Sprintf("decode: %s v%d is the current version", d.topic, version))
+	}
+	d.versions[version] = func(payload []byte) (T, error) {
+		var old Old
+		if err := json.Unmarshal(payload, &old); err != nil {
+			var zero T
+			return zero, err
+		}
+		return up(old)
+	}
+	return d
+}
+
+// WithLogAttrs sets the log attributes (key-value pairs, as for slog.Info)
+// that identify a decoded event, such as its execution_id. Returns d.
+func (d *Decoder[T]) WithLogAttrs(fn func(T) []any) *Decoder[T] {
+	d.logAttrs = fn
+	return d
+}
+
+// LogAttrs returns the log attributes of evt, or none.
+func (d *Decoder[T]) LogAttrs(evt T) []any {
+	if d.logAttrs == nil {
+		return nil
+	}
+	return d.logAttrs(evt)
+}
+
+// Topic returns the topic the decoder reads.
+func (d *Decoder[T]) Topic() string { return d.topic }
+
+// Current returns the schema version T corresponds to, which is the version
+// the scheduler itself produces.
+func (d *Decoder[T]) Current() int { return d.current }
+
+// Versions returns the schema versions the decoder accepts, in ascending order.
+func (d *Decoder[T]) Versions() []int {
+	out := make([]int, 0, len(d.versions))
+	for v := range d.versions {
+		out = append(out, v)
+	}
+	sort.Ints(out)
+	return out
+}
+
+// Decode reads payload's schema version and decodes it into T, upcasting
+// older versions. Unknown versions yield a *VersionError.
+func (d *Decoder[T]) Decode(payload []byte) (T, error) {
+	var zero T
+	version, err := Version(payload)
+	if err != nil {
+		return zero, fmt.Errorf("decode %s: %w", d.topic, err)
+	}
+	fn, ok := d.versions[version]
+	if !ok {
+		return zero, &VersionError{Topic: d.topic, Version: version, Supported: d.Versions()}
+	}
+	evt, err := fn(payload)
+	if err != nil {
+		return zero, fmt.Errorf("decode %s v%d: %w", d.topic, version, err)
+	}
+	return evt, nil
+}
+
+// Version returns payload's meta.schema_version, or 1 when it is absent, as
+// the schemas default it.
+func Version(payload []byte) (int, error) {
+	var env struct {
+		Meta struct {
+			SchemaVersion *int `json:"schema_version"`
+		} `json:"meta"`
+	}
+	if err := json.Unmarshal(payload, &env); err != nil {
+		return 0, err
+	}
+	if env.Meta.SchemaVersion == nil {
+		return 1, nil
+	}
+	return *env.Meta.SchemaVersion, nil
+}
+
+// VersionError reports a payload whose schema version has no decoder.
+type VersionError struct {
+	Topic     string
+	Version   int
+	Supported []int
+}
+
+func (e *VersionError) Error() string {
+	return fmt.Sprintf("decode %s: unsupported schema_version %d (supported %v)", e.Topic, e.Version, e.Supported)
+}
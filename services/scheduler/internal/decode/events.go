@@ -0,0 +1,35 @@
+package decode
+
+import (
+	"llm-your-business/schemas/events"
+	"llm-your-business/services/scheduler/internal/topics"
+)
+
+// Decoders for the topics the scheduler consumes. When a schema gains a new
+// version, point the decoder at the new generated type and register an
+// Upcast from the previous one, e.g.
+//
+//	Answer = Upcast(New[events.ObjectiveExecutionAnswerV2Json](topics.TopicObjectiveExecutionAnswer, 2),
+//		1, answerV1ToV2).WithLogAttrs(answerAttrs)
+var (
+	Question  = New[events.ObjectiveExecutionQuestionV1Json](topics.TopicObjectiveExecutionQuestion, 1).WithLogAttrs(questionAttrs)
+	Answer    = New[events.ObjectiveExecutionAnswerV1Json](topics.TopicObjectiveExecutionAnswer, 1).WithLogAttrs(answerAttrs)
+	Datapoint = New[events.ObjectiveDatapointV1Json](topics.TopicObjectiveDatapoint, 1).WithLogAttrs(datapointAttrs)
+	Manifest  = New[events.ObjectiveManifestV1Json](topics.TopicObjectiveManifest, 1).WithLogAttrs(manifestAttrs)
+)
+
+func questionAttrs(e events.ObjectiveExecutionQuestionV1Json) []any {
+	return []any{"manifest_id", e.Meta.ManifestId, "execution_id", e.Meta.ExecutionId, "question_id", e.Meta.QuestionId}
+}
+
+func answerAttrs(e events.ObjectiveExecutionAnswerV1Json) []any {
+	return []any{"manifest_id", e.Meta.ManifestId, "execution_id", e.Meta.ExecutionId, "question_id", e.Meta.QuestionId}
+}
+
+func datapointAttrs(e events.ObjectiveDatapointV1Json) []any {
+	return []any{"manifest_id", e.Meta.ManifestId, "execution_id", e.Meta.ExecutionId, "question_id", e.Meta.QuestionId}
+}
+
+func manifestAttrs(e events.ObjectiveManifestV1Json) []any {
+	return []any{"objective_id", e.Meta.ObjectiveId, "manifest_id", e.Meta.ManifestId, "execution_id", e.Meta.ExecutionId}
+}
@@ -0,0 +1,84 @@
+// Package drain lets components finish the work in hand when the service
+// shuts down. A component stops picking up new work when its context is
+// canceled, but runs each piece of work on a context from Group.Start that
+// outlives that cancellation. Group.Wait then waits for the work to finish,
+// and cancels it when its own deadline passes first.
+package drain
+
+import (
+	"context"
+	"sync"
+)
+
+// Group tracks running work. The zero value is ready to use.
+type Group struct {
+	mu      sync.Mutex
+	running int
+	idle    chan struct{} // closed when running drops to zero; nil when nobody waits
+	abort   context.Context
+	cancel  context.CancelFunc
+}
+
+// init sets up the abort context. Callers hold g.mu.
+func (g *Group) init() {
+	if g.abort == nil {
+		g.abort, g.cancel = context.WithCancel(context.Background())
+	}
+}
+
+// Start registers a piece of work. The returned context carries ctx's values
+// but is not canceled with ctx: only Wait giving up cancels it. done must be
+// called when the work is finished.
+func (g *Group) Start(ctx context.Context) (work context.Context, done func()) {
+	g.mu.Lock()
+	g.init()
+	g.running++
+	abort := g.abort
+	g.mu.Unlock()
+
+	work, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	if abort.Err() != nil {
+		cancel() // AfterFunc would only cancel it asynchronously
+	}
+	stop := context.AfterFunc(abort, cancel)
+	var once sync.Once
+	return work, func() {
+		once.Do(func() {
+			stop()
+			cancel()
+			g.mu.Lock()
+			defer g.mu.Unlock()
+			g.running--
+			if g.running == 0 && g.idle != nil {
+				close(g.idle)
+				g.idle = nil
+			}
+		})
+	}
+}
+
+// Wait blocks until no work is running. If ctx is done first, it cancels the
+// running work, and any started later, and returns ctx.Err().
+func (g *Group) Wait(ctx context.Context) error {
+	g.mu.Lock()
+	g.init()
+	if g.running == 0 {
+		g.mu.Unlock()
+		return nil
+	}
+	if g.idle == nil {
+		g.idle = make(chan struct{})
+	}
+	idle := g.idle
+	g.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		g.mu.Lock()
+		g.cancel()
+		g.mu.Unlock()
+		return ctx.Err()
+	}
+}
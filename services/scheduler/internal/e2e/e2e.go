@@ -0,0 +1,310 @@
+// Package e2e runs the scheduler end to end in process, on the in-memory
+// broker and store. One scheduled objective run is followed from the tick
+// through the manifest, the questions, the answers of a stand-in for the
+// suggestions service and the datapoints extracted from them, to the
+// completed execution.
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"llm-your-business/schemas/events"
+	model "llm-your-business/services/go/models"
+	"llm-your-business/services/scheduler/internal/budget"
+	"llm-your-business/services/scheduler/internal/config"
+	"llm-your-business/services/scheduler/internal/db"
+	dbmemory "llm-your-business/services/scheduler/internal/db/memory"
+	"llm-your-business/services/scheduler/internal/decode"
+	"llm-your-business/services/scheduler/internal/extract"
+	"llm-your-business/services/scheduler/internal/handlers"
+	"llm-your-business/services/scheduler/internal/kafka"
+	kafkamemory "llm-your-business/services/scheduler/internal/kafka/memory"
+	"llm-your-business/services/scheduler/internal/registry"
+	"llm-your-business/services/scheduler/internal/scheduler"
+	"llm-your-business/services/scheduler/internal/topics"
+)
+
+// The seeded objective. IDs are UUIDs because the event schemas require them.
+const (
+	ObjectiveID = "3f0e9d52-7c1a-4b8e-9f26-5d4c3b2a1e01"
+	questionA   = "3f0e9d52-7c1a-4b8e-9f26-5d4c3b2a1e0a"
+	questionB   = "3f0e9d52-7c1a-4b8e-9f26-5d4c3b2a1e0b"
+)
+
+// The seeded objective asks two questions with two models in one location.
+const expectedQuestions = 2 * 2
+
+// answerText is what the stand-in suggestions service answers every question
+// with; extraction turns it into a five-item ranked list.
+const answerText = "1. Acme\n2. Globex\n3. Initech\n4. Umbrella\n5. Hooli"
+
+const suggestionsGroup = "e2e-suggestions"
+
+// Report summarises a run.
+type Report struct {
+	ManifestID string
+	Execution  db.Execution
+	Questions  int // question events published
+	Answers    int // answer events published
+	Datapoints int // datapoint events published
+	SpendUSD   float64
+}
+
+// Config returns the scheduler configuration the harness runs with: defaults
+// as config.Load would set them, with short intervals.
+func Config() *config.Config {
+	return &config.Config{
+		AppEnv:              "e2e",
+		KafkaGroupID:        "scheduler",
+		KafkaClientID:       "scheduler",
+		KafkaWorkers:        4,
+		ExecutionTimeout:    time.Minute,
+		MaxFanout:           1000,
+		CatchUpPolicy:       config.CatchUpLatest,
+		CatchUpMaxRuns:      10,
+		BudgetAction:        config.BudgetDefer,
+		QuestionTimeout:     time.Minute,
+		QuestionMaxAttempts: 3,
+		WatchdogInterval:    time.Second,
+		RetryMaxAttempts:    2,
+		RetryInitialBackoff: 10 * time.Millisecond,
+		RetryMaxBackoff:     100 * time.Millisecond,
+		DedupeTTL:           time.Hour,
+		OutboxPollInterval:  50 * time.Millisecond,
+		TracesExporter:      config.TracesNone,
+	}
+}
+
+// Seed is the store content of the run: one active daily objective that has
+// never run, so the first tick runs today's slot.
+func Seed(now time.Time) dbmemory.Seed {
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -7)
+	return dbmemory.Seed{
+		Objectives: map[string]model.ObjectiveV1Json{
+			ObjectiveID: {
+				PublicId:      "e2e",
+				Title:         "Best project management tools",
+				LlmModels:     []string{string(events.ModelCHATGPT5), string(events.ModelCLAUDE35)},
+				ObjectiveType: "top_5_in_category",
+				Targets:       model.ObjectiveTargets{Language: []model.Language{"EN"}, Location: []string{"US"}},
+				PartnerId:     "e2e-partner",
+				Budget:        &model.Budget{DailyCostUSD: 100},
+				IsActive:      true,
+				RunSchedule:   model.RunScheduleDaily,
+				StartDate:     start,
+				CreatedAt:     start,
+				UpdatedAt:     start,
+			},
+		},
+		Questions: []model.QuestionV1Json{
+			{QuestionId: questionA, ObjectiveId: ObjectiveID, QuestionText: "Which project management tools are best for small teams?", Language: "EN"},
+			{QuestionId: questionB, ObjectiveId: ObjectiveID, QuestionText: "Which project management tools do agencies recommend?", Language: "EN"},
+		},
+	}
+}
+
+// Run wires the scheduler's components on an in-memory broker and store,
+// starts them, and waits until the seeded objective's run has completed and
+// every answer produced a datapoint. It fails when that does not happen
+// before ctx is done, or when any message was dead-lettered or quarantined.
+func Run(ctx context.Context) (Report, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	cfg := Config()
+
+	seed, err := json.Marshal(Seed(time.Now().UTC()))
+	if err != nil {
+		return Report{}, fmt.Errorf("encode seed: %w", err)
+	}
+	store := dbmemory.New()
+	if err := store.Seed(bytes.NewReader(seed)); err != nil {
+		return Report{}, err
+	}
+	broker := kafkamemory.New(3)
+	producer, err := kafka.NewProducer(broker)
+	if err != nil {
+		return Report{}, err
+	}
+	defer producer.Close(context.Background())
+
+	budgets, err := budget.New(store, cfg)
+	if err != nil {
+		return Report{}, err
+	}
+	reg := registry.New()
+	handlers.New(store, extract.New(producer), cfg).Register(reg)
+	budgets.Register(reg)
+	consumer, err := kafka.NewConsumer(cfg, broker, reg, producer)
+	if err != nil {
+		return Report{}, err
+	}
+	defer consumer.Close(context.Background())
+	// Join before anything is published; readers start at the next message.
+	questions := broker.Reader(kafka.ReaderConfig{Topic: topics.TopicObjectiveExecutionQuestion, GroupID: suggestionsGroup})
+	defer questions.Close()
+
+	svc := scheduler.New(producer, store, nil, budgets, cfg)
+	// Shut down as the scheduler does: stop, then drain before closing.
+	defer func() {
+		cancel()
+		dctx, dcancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer dcancel()
+		if err := consumer.Drain(dctx); err != nil {
+			slog.Error("e2e: consumer not drained", "err", err)
+		}
+		if err := svc.Drain(dctx); err != nil {
+			slog.Error("e2e: scheduler not drained", "err", err)
+		}
+	}()
+	errs := make(chan error, 4)
+	run := func(name string, fn func(context.Context) error) {
+		go func() {
+			if err := fn(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				errs <- fmt.Errorf("%s: %w", name, err)
+			}
+		}()
+	}
+	run("consumer", consumer.Start)
+	run("outbox relay", svc.RunOutboxRelay)
+	run("suggestions", func(ctx context.Context) error { return answerQuestions(ctx, questions, producer) })
+	run("scheduler", svc.Start)
+
+	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		report, done, err := check(ctx, store, broker)
+		if err != nil {
+			return report, err
+		}
+		if done {
+			return report, nil
+		}
+		select {
+		case err := <-errs:
+			return report, err
+		case <-ctx.Done():
+			return report, fmt.Errorf("run did not complete: %+v: %w", report, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// check reports how far the run has got and whether it is done.
+func check(ctx context.Context, store *dbmemory.Store, broker *kafkamemory.Broker) (Report, bool, error) {
+	var r Report
+	for _, t := range broker.Topics() {
+		if strings.HasSuffix(t, topics.DeadLetterSuffix) || strings.HasSuffix(t, topics.QuarantineSuffix) {
+			if n := len(broker.Messages(t)); n > 0 {
+				return r, false, fmt.Errorf("%d message(s) on %s", n, t)
+			}
+		}
+	}
+	r.Questions = len(broker.Messages(topics.TopicObjectiveExecutionQuestion))
+	r.Answers = len(broker.Messages(topics.TopicObjectiveExecutionAnswer))
+	r.Datapoints = len(broker.Messages(topics.TopicObjectiveDatapoint))
+
+	obj, err := store.FindObjective(ctx, ObjectiveID)
+	if err != nil {
+		return r, false, err
+	}
+	if len(obj.Runs) == 0 {
+		return r, false, nil
+	}
+	if len(obj.Runs) > 1 {
+		return r, false, fmt.Errorf("objective ran %d times, want once", len(obj.Runs))
+	}
+	r.ManifestID = obj.Runs[0].ManifestId
+	exec, ok := store.Execution(r.ManifestID)
+	if !ok {
+		return r, false, nil
+	}
+	r.Execution = exec
+	spend, err := store.ObjectiveSpendSince(ctx, ObjectiveID, time.Time{})
+	if err != nil {
+		return r, false, err
+	}
+	r.SpendUSD = spend.CostUSD
+
+	switch exec.Status {
+	case db.ExecutionStatusFailed:
+		return r, false, fmt.Errorf("execution failed: %s", exec.FailureReason)
+	case db.ExecutionStatusCompleted:
+	default:
+		return r, false, nil
+	}
+	if exec.ExpectedAnswers != expectedQuestions || r.Questions != expectedQuestions {
+		return r, false, fmt.Errorf("execution expected %d answers to %d questions, want %d", exec.ExpectedAnswers, r.Questions, expectedQuestions)
+	}
+	// PROCESSING is skipped when every answer beats the scheduler's own
+	// copy of the questions, which the lifecycle allows.
+	var history []string
+	for _, c := range exec.History {
+		history = append(history, c.Status)
+	}
+	if !slices.Equal(history, []string{db.ExecutionStatusPending, db.ExecutionStatusProcessing, db.ExecutionStatusCompleted}) &&
+		!slices.Equal(history, []string{db.ExecutionStatusPending, db.ExecutionStatusCompleted}) {
+		return r, false, fmt.Errorf("execution went through %v", history)
+	}
+	// Datapoints are published after the answer that completed the
+	// execution was stored; wait until the last one is recorded.
+	return r, r.Datapoints == expectedQuestions && exec.Datapoints == expectedQuestions, nil
+}
+
+// answerQuestions stands in for the suggestions service: it answers every
+// question event on r with answerText.
+func answerQuestions(ctx context.Context, r kafka.Reader, producer *kafka.Producer) error {
+	for {
+		m, err := r.FetchMessage(ctx)
+		if err != nil {
+			return err
+		}
+		q, err := decode.Question.Decode(m.Value)
+		if err != nil {
+			return fmt.Errorf("decode question: %w", err)
+		}
+		answer := events.ObjectiveExecutionAnswerV1Json{
+			Meta: events.ObjectiveExecutionAnswerV1JsonMeta{
+				SchemaVersion: decode.Answer.Current(),
+				CreatedAt:     int(time.Now().UTC().UnixMilli()),
+				Producer:      "e2e-suggestions",
+				RunAttempt:    q.Meta.RunAttempt,
+				ManifestId:    q.Meta.ManifestId,
+				ExecutionId:   q.Meta.ExecutionId,
+				ObjectiveId:   q.Meta.ObjectiveId,
+				QuestionId:    q.Meta.QuestionId,
+				Persona:       q.Meta.Persona,
+				Language:      q.Meta.Language,
+				Model:         q.Meta.Model,
+			},
+			Data: events.ObjectiveExecutionAnswerV1JsonData{
+				AnswerText:   answerText,
+				FinishReason: events.ObjectiveExecutionAnswerV1JsonDataFinishReasonSTOP,
+				ExecutionMetadata: events.ObjectiveExecutionAnswerV1JsonDataExecutionMetadata{
+					LatencyMs:    5,
+					InputTokens:  120,
+					OutputTokens: 40,
+					HttpStatus:   200,
+				},
+			},
+		}
+		payload, err := json.Marshal(answer)
+		if err != nil {
+			return fmt.Errorf("marshal answer: %w", err)
+		}
+		if err := producer.Publish(ctx, topics.TopicObjectiveExecutionAnswer, m.Key, payload); err != nil {
+			return fmt.Errorf("publish answer: %w", err)
+		}
+		if err := r.CommitMessages(ctx, m); err != nil {
+			return err
+		}
+		slog.DebugContext(ctx, "e2e: answered question", "question_id", q.Meta.QuestionId, "model", q.Meta.Model)
+	}
+}
@@ -0,0 +1,52 @@
+package e2e
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"llm-your-business/services/scheduler/internal/db"
+	"llm-your-business/services/scheduler/internal/logging"
+)
+
+func TestRun(t *testing.T) {
+	if testing.Short() {
+		t.Skip("end-to-end run skipped in short mode")
+	}
+	level := slog.LevelWarn
+	if testing.Verbose() {
+		level = slog.LevelDebug
+	}
+	logging.Setup(level)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	report, err := Run(ctx)
+	if err != nil {
+		t.Fatalf("run failed: %v (report %+v)", err, report)
+	}
+	if report.Execution.Status != db.ExecutionStatusCompleted {
+		t.Fatalf("execution %s is %s, want %s", report.ManifestID, report.Execution.Status, db.ExecutionStatusCompleted)
+	}
+	counts := []struct {
+		name string
+		got  int
+	}{
+		{"question events", report.Questions},
+		{"answer events", report.Answers},
+		{"datapoint events", report.Datapoints},
+		{"expected answers", report.Execution.ExpectedAnswers},
+		{"received answers", report.Execution.ReceivedAnswers},
+		{"recorded datapoints", report.Execution.Datapoints},
+	}
+	for _, c := range counts {
+		if c.got != expectedQuestions {
+			t.Errorf("%s = %d, want %d", c.name, c.got, expectedQuestions)
+		}
+	}
+	if report.SpendUSD <= 0 {
+		t.Errorf("spend = %v, want the answers' cost recorded", report.SpendUSD)
+	}
+}
@@ -0,0 +1,131 @@
+// Package extract turns free-text LLM answers into structured datapoints.
+package extract
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"llm-your-business/schemas/events"
+	"llm-your-business/services/scheduler/internal/decode"
+)
+
+// NormalizerVersion identifies the label cleaning rules applied by this
+// package. Bump it whenever cleanLabel or the line patterns change so that
+// downstream reports can tell datapoints from different rule sets apart.
+const NormalizerVersion = "ranked-list/2"
+
+// Extraction spec IDs per question type. They are stable identifiers for
+// "what we asked the parser to pull out" and must stay constant across releases.
+const (
+	SpecTop5RankedList  = "5b0c3a8e-2f41-4c8e-9b7a-1d6e0f3c5a01"
+	SpecTop10RankedList = "5b0c3a8e-2f41-4c8e-9b7a-1d6e0f3c5a02"
+)
+
+// Publisher is the subset of kafka.Producer the extractor needs.
+type Publisher interface {
+	PublishObjectiveDatapoint(ctx context.Context, evt events.ObjectiveDatapointV1Json) error
+}
+
+// Extractor parses answers and publishes the resulting datapoints.
+type Extractor struct {
+	pub Publisher
+}
+
+func New(pub Publisher) *Extractor { return &Extractor{pub: pub} }
+
+// Process extracts a datapoint from the answer and publishes it. Answers that
+// yield no ranked items are logged and skipped.
+func (x *Extractor) Process(ctx context.Context, answer events.ObjectiveExecutionAnswerV1Json, qt events.QuestionType) error {
+	dp, ok, err := Extract(answer, qt)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		slog.InfoContext(ctx, "extract: no ranked items in answer; skipping", "finish_reason", answer.Data.FinishReason)
+		return nil
+	}
+	if err := x.pub.PublishObjectiveDatapoint(ctx, dp); err != nil {
+		return fmt.Errorf("publish datapoint: %w", err)
+	}
+	return nil
+}
+
+// Extract builds an ObjectiveDatapoint from an answer. ok is false when the
+// answer errored or contained no recognizable list.
+func Extract(answer events.ObjectiveExecutionAnswerV1Json, qt events.QuestionType) (events.ObjectiveDatapointV1Json, bool, error) {
+	var limit int
+	var spec string
+	switch qt {
+	case events.QuestionTypeTop5:
+		limit, spec = 5, SpecTop5RankedList
+	case events.QuestionTypeTop10:
+		limit, spec = 10, SpecTop10RankedList
+	default:
+		return events.ObjectiveDatapointV1Json{}, false, fmt.Errorf("unsupported question_type: %s", qt)
+	}
+	if answer.Data.FinishReason == events.ObjectiveExecutionAnswerV1JsonDataFinishReasonERROR {
+		return events.ObjectiveDatapointV1Json{}, false, nil
+	}
+
+	list := parseRankedList(answer.Data.AnswerText, limit)
+	if len(list.labels) == 0 {
+		return events.ObjectiveDatapointV1Json{}, false, nil
+	}
+
+	data := make(events.ObjectiveDatapointV1JsonDataData, len(list.labels))
+	for rank, label := range list.labels {
+		data[strconv.Itoa(rank)] = label
+	}
+
+	runAttempt := answer.Meta.RunAttempt
+	if runAttempt < 1 {
+		runAttempt = 1
+	}
+	return events.ObjectiveDatapointV1Json{
+		Meta: events.ObjectiveDatapointV1JsonMeta{
+			SchemaVersion: decode.Datapoint.Current(),
+			CreatedAt:     int(time.Now().UTC().UnixMilli()),
+			Producer:      "scheduler",
+			RunAttempt:    runAttempt,
+			ManifestId:    answer.Meta.ManifestId,
+			ExecutionId:   answer.Meta.ExecutionId,
+			QuestionId:    answer.Meta.QuestionId,
+			QuestionType:  qt,
+			Persona:       answer.Meta.Persona,
+			Language:      answer.Meta.Language,
+			Model:         answer.Meta.Model,
+		},
+		Data: events.ObjectiveDatapointV1JsonData{
+			ExtractionSpecId:  spec,
+			Data:              data,
+			Confidence:        confidence(list, limit, answer.Data.FinishReason),
+			NormalizerVersion: NormalizerVersion,
+		},
+	}, true, nil
+}
+
+// confidence scores how much the parsed list can be trusted, in [0, 1].
+// A complete, explicitly numbered, in-order list that finished normally scores 1.
+func confidence(list rankedList, limit int, finish events.ObjectiveExecutionAnswerV1JsonDataFinishReason) float64 {
+	c := float64(len(list.labels)) / float64(limit)
+	if !list.numbered {
+		c *= 0.7
+	}
+	if list.gaps {
+		c *= 0.8
+	}
+	if finish != events.ObjectiveExecutionAnswerV1JsonDataFinishReasonSTOP {
+		// LENGTH / CONTENT_FILTER: the list may have been cut short.
+		c *= 0.6
+	}
+	if c > 1 {
+		c = 1
+	}
+	if c < 0 {
+		c = 0
+	}
+	return c
+}
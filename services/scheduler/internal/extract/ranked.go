@@ -0,0 +1,112 @@
+package extract
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// "1. Foo", "2) Foo", "#3 Foo", "**4.** Foo", "5: Foo". The number needs
+	// a "#" before it or a separator after it, so that an intro such as
+	// "5 reasons to choose..." is not read as rank 5.
+	numberedLine = regexp.MustCompile(`^(?:#{1,6}\s*)?(?:\*\*|__)?(?:#(\d{1,2})|(\d{1,2})\s*[.):])(?:\*\*|__)?\s+(.+)$`)
+	// "- Foo", "* Foo", "• Foo"
+	bulletLine = regexp.MustCompile(`^[-*•]\s+(.+)$`)
+	// Markdown emphasis and inline code markers.
+	markdown = strings.NewReplacer("**", "", "__", "", "`", "")
+)
+
+// labelSeparators split an item's name from its explanation, e.g.
+// "Wix – easy drag-and-drop builder". Checked in order.
+var labelSeparators = []string{" – ", " — ", " - ", ": ", " (", ", "}
+
+// rankedList is the result of parsing a free-text answer.
+type rankedList struct {
+	labels   map[int]string
+	numbered bool // ranks came from explicit numbers rather than bullet order
+	gaps     bool // explicit ranks were missing, duplicated or out of order
+}
+
+// parseRankedList extracts up to limit ranked labels from text. Explicitly
+// numbered lines win; bullet lists are ranked by order of appearance.
+func parseRankedList(text string, limit int) rankedList {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	out := rankedList{labels: make(map[int]string, limit), numbered: true}
+	seen := make(map[string]bool, limit)
+	next := 1
+	for _, line := range lines {
+		m := numberedLine.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		rank, err := strconv.Atoi(m[1] + m[2])
+		if err != nil || rank < 1 || rank > limit {
+			continue
+		}
+		label := cleanLabel(m[3])
+		if label == "" || seen[strings.ToLower(label)] {
+			out.gaps = true
+			continue
+		}
+		if _, dup := out.labels[rank]; dup {
+			// A second "1." usually starts a new list (e.g. pros/cons); keep the first.
+			out.gaps = true
+			continue
+		}
+		if rank != next {
+			out.gaps = true
+		}
+		out.labels[rank] = label
+		seen[strings.ToLower(label)] = true
+		next = rank + 1
+	}
+	if len(out.labels) > 0 {
+		return out
+	}
+
+	out.numbered = false
+	rank := 1
+	for _, line := range lines {
+		if rank > limit {
+			break
+		}
+		m := bulletLine.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		label := cleanLabel(m[1])
+		if label == "" || seen[strings.ToLower(label)] {
+			continue
+		}
+		out.labels[rank] = label
+		seen[strings.ToLower(label)] = true
+		rank++
+	}
+	return out
+}
+
+// cleanLabel strips markdown and trailing explanations from a list item,
+// leaving the recommended name.
+func cleanLabel(s string) string {
+	s = markdown.Replace(s)
+	s = strings.TrimSpace(s)
+	// Links: "[Wix](https://wix.com)" -> "Wix"
+	if strings.HasPrefix(s, "[") {
+		if i := strings.Index(s, "]("); i > 0 {
+			rest := ""
+			if j := strings.Index(s[i:], ")"); j >= 0 {
+				rest = s[i+j+1:]
+			}
+			s = s[1:i] + rest
+		}
+	}
+	for _, sep := range labelSeparators {
+		if i := strings.Index(s, sep); i > 0 {
+			s = s[:i]
+		}
+	}
+	s = strings.TrimRight(strings.TrimSpace(s), ".:;,-–—")
+	return strings.TrimSpace(s)
+}
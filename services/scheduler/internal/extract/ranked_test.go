@@ -0,0 +1,141 @@
+package extract
+
+import (
+	"maps"
+	"testing"
+
+	"llm-your-business/schemas/events"
+)
+
+func TestParseRankedList(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		limit    int
+		want     map[int]string
+		numbered bool
+		gaps     bool
+	}{
+		{
+			name: "markdown numbered list with explanations",
+			text: "Here are the top 5 website builders for small businesses:\n\n" +
+				"1. **Wix** – easy drag-and-drop builder with hundreds of templates.\n" +
+				"2. **Squarespace** – polished designs, great for portfolios.\n" +
+				"3. **Shopify**: best if you mainly sell online.\n" +
+				"4. [WordPress.com](https://wordpress.com) - flexible, huge plugin ecosystem.\n" +
+				"5. **Webflow** (for designers who want full control)\n\n" +
+				"Each of these offers a free trial.",
+			limit:    5,
+			want:     map[int]string{1: "Wix", 2: "Squarespace", 3: "Shopify", 4: "WordPress.com", 5: "Webflow"},
+			numbered: true,
+		},
+		{
+			name: "intro line starting with a number is not a rank",
+			text: "5 reasons to choose a local bakery:\n\n" +
+				"1) Fresh bread daily\n" +
+				"2) Supports the neighbourhood\n",
+			limit:    5,
+			want:     map[int]string{1: "Fresh bread daily", 2: "Supports the neighbourhood"},
+			numbered: true,
+		},
+		{
+			name: "bold numbers and heading ranks",
+			text: "### #1 Notion\nAll-in-one workspace.\n\n" +
+				"**2.** Trello, for simple boards\n" +
+				"### 3: Asana\n",
+			limit:    5,
+			want:     map[int]string{1: "Notion", 2: "Trello", 3: "Asana"},
+			numbered: true,
+		},
+		{
+			name:     "second list keeps the first ranks",
+			text:     "1. Stripe\n2. PayPal\n\nPros of Stripe:\n1. Great API\n2. Fast payouts\n",
+			limit:    5,
+			want:     map[int]string{1: "Stripe", 2: "PayPal"},
+			numbered: true,
+			gaps:     true,
+		},
+		{
+			name:     "out of order ranks",
+			text:     "1. Alpha\n3. Gamma\n2. Beta\n",
+			limit:    5,
+			want:     map[int]string{1: "Alpha", 2: "Beta", 3: "Gamma"},
+			numbered: true,
+			gaps:     true,
+		},
+		{
+			name:     "ranks past the limit are dropped",
+			text:     "1. A\n2. B\n3. C\n4. D\n5. E\n6. F\n",
+			limit:    5,
+			want:     map[int]string{1: "A", 2: "B", 3: "C", 4: "D", 5: "E"},
+			numbered: true,
+		},
+		{
+			name:  "bullet list ranked by order",
+			text:  "Good options include:\n- Mailchimp – free tier\n* ConvertKit\n• Mailchimp\n- Brevo\n",
+			limit: 5,
+			want:  map[int]string{1: "Mailchimp", 2: "ConvertKit", 3: "Brevo"},
+		},
+		{
+			name:  "prose without a list",
+			text:  "It depends on your budget. 3 of my favourites are hard to rank.",
+			limit: 5,
+			want:  map[int]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseRankedList(tt.text, tt.limit)
+			if !maps.Equal(got.labels, tt.want) {
+				t.Errorf("labels = %v, want %v", got.labels, tt.want)
+			}
+			if len(tt.want) > 0 && got.numbered != tt.numbered {
+				t.Errorf("numbered = %v, want %v", got.numbered, tt.numbered)
+			}
+			if got.gaps != tt.gaps {
+				t.Errorf("gaps = %v, want %v", got.gaps, tt.gaps)
+			}
+		})
+	}
+}
+
+func TestExtract(t *testing.T) {
+	answer := events.ObjectiveExecutionAnswerV1Json{
+		Meta: events.ObjectiveExecutionAnswerV1JsonMeta{ManifestId: "m1", ExecutionId: "e1", QuestionId: "q1"},
+		Data: events.ObjectiveExecutionAnswerV1JsonData{
+			AnswerText:   "1. Wix\n2. Squarespace\n3. Shopify\n4. WordPress\n5. Webflow\n",
+			FinishReason: events.ObjectiveExecutionAnswerV1JsonDataFinishReasonSTOP,
+		},
+	}
+
+	dp, ok, err := Extract(answer, events.QuestionTypeTop5)
+	if err != nil || !ok {
+		t.Fatalf("Extract = ok %v, err %v", ok, err)
+	}
+	if dp.Data.Confidence != 1 {
+		t.Errorf("confidence = %v, want 1", dp.Data.Confidence)
+	}
+	if dp.Data.Data["1"] != "Wix" || dp.Data.Data["5"] != "Webflow" {
+		t.Errorf("data = %v", dp.Data.Data)
+	}
+	if dp.Meta.RunAttempt != 1 {
+		t.Errorf("run_attempt = %d, want 1", dp.Meta.RunAttempt)
+	}
+
+	// The same list as a top 10 is half complete; cut short, it scores lower still.
+	dp, _, _ = Extract(answer, events.QuestionTypeTop10)
+	full := dp.Data.Confidence
+	answer.Data.FinishReason = events.ObjectiveExecutionAnswerV1JsonDataFinishReasonLENGTH
+	dp, _, _ = Extract(answer, events.QuestionTypeTop10)
+	if full != 0.5 || dp.Data.Confidence >= full {
+		t.Errorf("top 10 confidence = %v, cut short %v", full, dp.Data.Confidence)
+	}
+
+	answer.Data.FinishReason = events.ObjectiveExecutionAnswerV1JsonDataFinishReasonERROR
+	if _, ok, err := Extract(answer, events.QuestionTypeTop5); ok || err != nil {
+		t.Errorf("errored answer: ok %v, err %v", ok, err)
+	}
+	if _, _, err := Extract(answer, events.QuestionType("OPEN")); err == nil {
+		t.Error("unsupported question type: want error")
+	}
+}
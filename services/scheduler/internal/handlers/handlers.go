@@ -2,38 +2,184 @@ package handlers
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
 
 	"llm-your-business/schemas/events"
+	"llm-your-business/services/scheduler/internal/config"
+	"llm-your-business/services/scheduler/internal/db"
+	"llm-your-business/services/scheduler/internal/decode"
+	"llm-your-business/services/scheduler/internal/extract"
+	"llm-your-business/services/scheduler/internal/registry"
 )
 
-type Handlers struct{}
+// Handlers handle one decoded event each. Log records take the event's IDs
+// from ctx, where the registry attaches them with logging.With.
+type Handlers struct {
+	db               db.Store // may be nil: nothing is stored
+	extractor        *extract.Extractor
+	executionTimeout time.Duration
+}
 
-func New() *Handlers { return &Handlers{} }
+func New(store db.Store, extractor *extract.Extractor, cfg *config.Config) *Handlers {
+	return &Handlers{db: store, extractor: extractor, executionTimeout: cfg.ExecutionTimeout}
+}
 
-func (h *Handlers) HandleObjectiveExecutionQuestion(ctx context.Context, e events.ObjectiveExecutionQuestionV1Json) error {
-	// TODO: implement scheduling logic for ObjectiveExecutionQuestion events
-	// - validate payload
-	// - enqueue downstream job if needed
-	// - emit follow-up events
-	log.Printf("received ObjectiveExecutionQuestion: execution_id=%s question_id=%s", e.Meta.ExecutionId, e.Meta.QuestionId)
+// Register subscribes the handlers to their topics.
+func (h *Handlers) Register(r *registry.Registry) {
+	registry.Subscribe(r, decode.Question, "save-question", h.HandleObjectiveExecutionQuestion)
+	registry.Subscribe(r, decode.Answer, "save-answer", h.HandleObjectiveExecutionAnswer)
+	registry.Subscribe(r, decode.Datapoint, "datapoint", h.HandleObjectiveDatapoint)
+	registry.Subscribe(r, decode.Manifest, "record-manifest", h.HandleObjectiveManifest)
+}
+
+// Event kinds used as the first part of the dedupe key.
+const (
+	kindQuestion  = "question"
+	kindAnswer    = "answer"
+	kindDatapoint = "datapoint"
+)
+
+// claimTimeout is how long a claim keeps other copies of an event away. A
+// consumer that died mid-handler leaves its claim behind; once it is this old
+// a redelivered copy takes it over. It is about as long as the default retry
+// backoffs add up to, so the retries of a copy that met the claim outlast it.
+const claimTimeout = 30 * time.Second
+
+// errClaimHeld fails a copy of an event that another consumer is handling,
+// such as the same event arriving on its source and retry topic at once. The
+// copy goes through the retry path and is skipped once the other is done.
+var errClaimHeld = errors.New("event is being handled by another consumer")
+
+// once runs fn unless an event of kind with the same (execution_id,
+// question_id, run_attempt) was already handled. The event is claimed before
+// fn runs, so concurrent copies cannot both run it, and marked processed after
+// fn succeeds. A failed fn releases the claim, so redeliveries retry it.
+func (h *Handlers) once(ctx context.Context, kind, executionID, questionID string, runAttempt int, fn func() error) error {
+	if h.db == nil {
+		return fn()
+	}
+	state, err := h.db.ClaimEvent(ctx, kind, executionID, questionID, runAttempt, claimTimeout)
+	if err != nil {
+		return fmt.Errorf("dedupe claim: %w", err)
+	}
+	switch state {
+	case db.ClaimDone:
+		slog.InfoContext(ctx, "duplicate event skipped", "kind", kind, "run_attempt", runAttempt)
+		return nil
+	case db.ClaimHeld:
+		return errClaimHeld
+	}
+	if err := fn(); err != nil {
+		// Release even when ctx was canceled by shutdown.
+		if rerr := h.db.ReleaseEvent(context.WithoutCancel(ctx), kind, executionID, questionID, runAttempt); rerr != nil {
+			slog.WarnContext(ctx, "dedupe release failed; claim expires on its own", "err", rerr)
+		}
+		return err
+	}
+	if err := h.db.MarkProcessed(ctx, kind, executionID, questionID, runAttempt); err != nil {
+		return fmt.Errorf("dedupe record: %w", err)
+	}
 	return nil
 }
 
+// fallbackDeadline is the deadline of an execution created by a question or
+// answer that beat its manifest. The manifest replaces it with one counted
+// from its own created_at; if the manifest is lost, the execution still
+// fails once this passes.
+func (h *Handlers) fallbackDeadline() time.Time {
+	return time.Now().UTC().Add(h.executionTimeout)
+}
+
+// HandleObjectiveExecutionQuestion records the question so its question_type
+// is known when the answer comes back for extraction.
+func (h *Handlers) HandleObjectiveExecutionQuestion(ctx context.Context, e events.ObjectiveExecutionQuestionV1Json) error {
+	slog.InfoContext(ctx, "received ObjectiveExecutionQuestion")
+	if h.db == nil {
+		return nil
+	}
+	return h.once(ctx, kindQuestion, e.Meta.ExecutionId, e.Meta.QuestionId, e.Meta.RunAttempt, func() error {
+		if err := h.db.SaveQuestion(ctx, e, h.fallbackDeadline()); err != nil {
+			return fmt.Errorf("save question: %w", err)
+		}
+		return nil
+	})
+}
+
+// HandleObjectiveExecutionAnswer stores the answer against its manifest,
+// counts it towards the execution's completion and extracts its datapoint.
 func (h *Handlers) HandleObjectiveExecutionAnswer(ctx context.Context, e events.ObjectiveExecutionAnswerV1Json) error {
-	// TODO: implement scheduling logic for ObjectiveExecutionAnswer events
-	log.Printf("received ObjectiveExecutionAnswer: execution_id=%s question_id=%s", e.Meta.ExecutionId, e.Meta.QuestionId)
-	return nil
+	slog.InfoContext(ctx, "received ObjectiveExecutionAnswer")
+	if h.db == nil {
+		return nil
+	}
+	return h.once(ctx, kindAnswer, e.Meta.ExecutionId, e.Meta.QuestionId, e.Meta.RunAttempt, func() error {
+		return h.handleAnswer(ctx, e)
+	})
 }
 
+func (h *Handlers) handleAnswer(ctx context.Context, e events.ObjectiveExecutionAnswerV1Json) error {
+	inserted, err := h.db.SaveAnswer(ctx, e, h.fallbackDeadline())
+	if err != nil {
+		return fmt.Errorf("save answer: %w", err)
+	}
+	if !inserted {
+		slog.InfoContext(ctx, "answer already stored; not counted again")
+	}
+
+	if h.extractor == nil {
+		return nil
+	}
+	qt, err := h.db.FindQuestionType(ctx, e.Meta.ManifestId, e.Meta.QuestionId)
+	if err != nil {
+		return fmt.Errorf("find question type: %w", err)
+	}
+	if qt == "" {
+		return fmt.Errorf("question not recorded yet: manifest_id=%s question_id=%s", e.Meta.ManifestId, e.Meta.QuestionId)
+	}
+	return h.extractor.Process(ctx, e, qt)
+}
+
+// HandleObjectiveDatapoint moves the datapoint's question to EXTRACTED and
+// counts it against the execution. A datapoint whose question has no stored
+// answer is an illegal transition; it is logged and dropped, since
+// redelivering it cannot change that.
 func (h *Handlers) HandleObjectiveDatapoint(ctx context.Context, e events.ObjectiveDatapointV1Json) error {
-	// TODO: implement scheduling logic for ObjectiveDatapoint events
-	log.Printf("received ObjectiveDatapoint: manifest_id=%s question_id=%s", e.Meta.ManifestId, e.Meta.QuestionId)
-	return nil
+	slog.InfoContext(ctx, "received ObjectiveDatapoint")
+	if h.db == nil {
+		return nil
+	}
+	return h.once(ctx, kindDatapoint, e.Meta.ExecutionId, e.Meta.QuestionId, e.Meta.RunAttempt, func() error {
+		recorded, err := h.db.RecordDatapoint(ctx, e)
+		if errors.Is(err, db.ErrIllegalTransition) {
+			slog.ErrorContext(ctx, "datapoint rejected", "err", err)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("record datapoint: %w", err)
+		}
+		if !recorded {
+			slog.InfoContext(ctx, "question already extracted; datapoint not counted again")
+		}
+		return nil
+	})
 }
 
+// HandleObjectiveManifest records how many questions the manifest contains and
+// the deadline by which all of their answers must arrive.
 func (h *Handlers) HandleObjectiveManifest(ctx context.Context, e events.ObjectiveManifestV1Json) error {
-	// TODO: implement scheduling logic for ObjectiveManifest events
-	log.Printf("received ObjectiveManifest: execution_id=%s manifest_id=%s", e.Meta.ExecutionId, e.Meta.ManifestId)
+	slog.InfoContext(ctx, "received ObjectiveManifest", "questions", len(e.Data.Questions))
+	if h.db == nil {
+		return nil
+	}
+	created := time.UnixMilli(int64(e.Meta.CreatedAt)).UTC()
+	if e.Meta.CreatedAt <= 0 {
+		created = time.Now().UTC()
+	}
+	if err := h.db.RecordManifest(ctx, e, created.Add(h.executionTimeout)); err != nil {
+		return fmt.Errorf("record manifest: %w", err)
+	}
 	return nil
 }
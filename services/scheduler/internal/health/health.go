@@ -0,0 +1,86 @@
+// Package health serves liveness (/healthz) and readiness (/readyz) probes
+// built from named checks.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// checkTimeout bounds one probe request; checks run concurrently.
+const checkTimeout = 3 * time.Second
+
+// CheckFunc returns nil when the checked dependency is healthy.
+type CheckFunc func(ctx context.Context) error
+
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// Handler holds the liveness and readiness checks. Liveness should only fail
+// when restarting the process would help (a stuck loop); readiness also
+// covers the dependencies the process needs to do useful work.
+type Handler struct {
+	live  []check
+	ready []check
+}
+
+func New() *Handler { return &Handler{} }
+
+// Live adds a check to both /healthz and /readyz.
+func (h *Handler) Live(name string, fn CheckFunc) {
+	h.live = append(h.live, check{name, fn})
+	h.ready = append(h.ready, check{name, fn})
+}
+
+// Ready adds a check to /readyz only.
+func (h *Handler) Ready(name string, fn CheckFunc) {
+	h.ready = append(h.ready, check{name, fn})
+}
+
+// Register mounts GET /healthz and GET /readyz on mux.
+func (h *Handler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) { serve(w, r, h.live) })
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) { serve(w, r, h.ready) })
+}
+
+// serve runs checks and answers 200 when all pass, 503 otherwise, with each
+// check's result in the body.
+func serve(w http.ResponseWriter, r *http.Request, checks []check) {
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+
+	results := make(map[string]string, len(checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	ok := true
+	for _, c := range checks {
+		c := c
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := c.fn(ctx)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				results[c.name] = err.Error()
+				ok = false
+				return
+			}
+			results[c.name] = "ok"
+		}()
+	}
+	wg.Wait()
+
+	status, code := "ok", http.StatusOK
+	if !ok {
+		status, code = "fail", http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]any{"status": status, "checks": results})
+}
@@ -0,0 +1,159 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	kafka "github.com/segmentio/kafka-go"
+
+	"llm-your-business/services/scheduler/internal/config"
+)
+
+// Broker is the transport Producer and Consumer run on. NewBroker connects
+// to Kafka; package memory provides an in-process broker for tests.
+type Broker interface {
+	// Writer returns a writer for topic. Topics are created on first use.
+	Writer(topic string) Writer
+	// Reader joins a consumer group on one topic.
+	Reader(cfg ReaderConfig) Reader
+	// Ping checks that a broker answers a metadata request.
+	Ping(ctx context.Context) error
+	// CheckTopics checks that the metadata of topics can be read, in one
+	// request. Topics that do not exist yet pass.
+	CheckTopics(ctx context.Context, topics ...string) error
+}
+
+// Writer publishes messages to one topic.
+type Writer interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// Reader fetches the messages of one topic for its consumer group. Fetching
+// does not commit; offsets are committed explicitly with CommitMessages, and
+// a group member that joins later resumes after the last committed offset.
+type Reader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// ReaderConfig selects what a Reader consumes.
+type ReaderConfig struct {
+	Topic   string
+	GroupID string
+	// FromStart starts a group without committed offsets at the oldest
+	// message instead of the next one written.
+	FromStart bool
+}
+
+// kafkaBroker is the Broker backed by segmentio/kafka-go.
+type kafkaBroker struct {
+	brokers []string
+	dialer  *kafka.Dialer
+	client  *kafka.Client // metadata requests, over pooled connections
+}
+
+// NewBroker returns a Broker connecting to KAFKA_BOOTSTRAP_SERVERS.
+func NewBroker(cfg *config.Config) Broker {
+	return &kafkaBroker{
+		brokers: cfg.KafkaBrokers,
+		dialer: &kafka.Dialer{
+			Timeout:   10 * time.Second,
+			DualStack: true,
+			ClientID:  cfg.KafkaClientID,
+		},
+		client: &kafka.Client{
+			Addr:      kafka.TCP(cfg.KafkaBrokers...),
+			Transport: &kafka.Transport{DialTimeout: 10 * time.Second, ClientID: cfg.KafkaClientID},
+		},
+	}
+}
+
+// Writer partitions by key: the messages of one execution share a key and
+// must stay in one partition to be handled in order. Murmur2 partitions keys
+// like the Java client and aiokafka, which the LLM service publishes with.
+func (b *kafkaBroker) Writer(topic string) Writer {
+	return &kafka.Writer{
+		Addr:         kafka.TCP(b.brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Murmur2Balancer{},
+		RequiredAcks: kafka.RequireAll,
+		Async:        false,
+		BatchTimeout: 50 * time.Millisecond,
+		// Retry and dead-letter topics are created on first use.
+		AllowAutoTopicCreation: true,
+	}
+}
+
+func (b *kafkaBroker) Reader(cfg ReaderConfig) Reader {
+	start := kafka.LastOffset
+	if cfg.FromStart {
+		start = kafka.FirstOffset
+	}
+	return kafka.NewReader(kafka.ReaderConfig{
+		Brokers:               b.brokers,
+		GroupID:               cfg.GroupID,
+		Topic:                 cfg.Topic,
+		StartOffset:           start,
+		HeartbeatInterval:     0,
+		WatchPartitionChanges: true,
+		// Commit synchronously from the caller; never auto-commit on read.
+		CommitInterval: 0,
+		// Min/MaxBytes and other tuning can be added later
+	})
+}
+
+func (b *kafkaBroker) Ping(ctx context.Context) error {
+	conn, err := dialAny(ctx, b.dialer, b.brokers)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.Brokers(); err != nil {
+		return fmt.Errorf("read brokers: %w", err)
+	}
+	return nil
+}
+
+func (b *kafkaBroker) CheckTopics(ctx context.Context, topics ...string) error {
+	if len(topics) == 0 {
+		return nil // an empty request would read every topic of the cluster
+	}
+	res, err := b.client.Metadata(ctx, &kafka.MetadataRequest{Topics: topics})
+	if err != nil {
+		return fmt.Errorf("read metadata: %w", err)
+	}
+	var errs []error
+	for _, t := range res.Topics {
+		if t.Error != nil && !errors.Is(t.Error, kafka.UnknownTopicOrPartition) {
+			errs = append(errs, fmt.Errorf("topic=%s: %w", t.Name, t.Error))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// dialAny connects to the first broker that accepts a connection. The
+// connection's deadline follows ctx.
+func dialAny(ctx context.Context, d *kafka.Dialer, brokers []string) (*kafka.Conn, error) {
+	var lastErr error
+	for _, b := range brokers {
+		conn, err := d.DialContext(ctx, "tcp", b)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if deadline, ok := ctx.Deadline(); ok {
+			_ = conn.SetDeadline(deadline)
+		} else {
+			_ = conn.SetDeadline(time.Now().Add(d.Timeout))
+		}
+		return conn, nil
+	}
+	if lastErr == nil {
+		lastErr = errors.New("no brokers configured")
+	}
+	return nil, fmt.Errorf("dial kafka: %w", lastErr)
+}
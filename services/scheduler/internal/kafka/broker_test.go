@@ -0,0 +1,33 @@
+package kafka
+
+import (
+	"fmt"
+	"testing"
+
+	kafka "github.com/segmentio/kafka-go"
+
+	"llm-your-business/services/scheduler/internal/config"
+)
+
+// TestWriterPartitionsByKey guards the ordering the keyed workers and held
+// back commits rely on: every message of a key goes to one partition.
+func TestWriterPartitionsByKey(t *testing.T) {
+	w, ok := NewBroker(&config.Config{KafkaBrokers: []string{"localhost:9092"}}).Writer("objective.execution.answer").(*kafka.Writer)
+	if !ok {
+		t.Fatal("the Kafka broker's writer is not a *kafka.Writer")
+	}
+	if _, ok := w.Balancer.(*kafka.Murmur2Balancer); !ok {
+		t.Fatalf("writer balancer is %T, want a key-hash *kafka.Murmur2Balancer", w.Balancer)
+	}
+
+	partitions := []int{0, 1, 2, 3, 4, 5}
+	for _, key := range []string{"e-1", "e-2", "3f0e9d52-7c1a-4b8e-9f26-5d4c3b2a1e01"} {
+		want := w.Balancer.Balance(kafka.Message{Key: []byte(key)}, partitions...)
+		for i := 0; i < 20; i++ {
+			m := kafka.Message{Key: []byte(key), Value: []byte(fmt.Sprintf(`{"n":%d}`, i))}
+			if got := w.Balancer.Balance(m, partitions...); got != want {
+				t.Fatalf("key %s went to partitions %d and %d", key, want, got)
+			}
+		}
+	}
+}
@@ -2,48 +2,83 @@ package kafka
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
-	"strings"
+	"log/slog"
 	"sync"
+	"time"
 
 	kafka "github.com/segmentio/kafka-go"
 
-	"llm-your-business/schemas/events"
 	"llm-your-business/services/scheduler/internal/config"
-	"llm-your-business/services/scheduler/internal/handlers"
+	"llm-your-business/services/scheduler/internal/drain"
+	"llm-your-business/services/scheduler/internal/logging"
+	"llm-your-business/services/scheduler/internal/metrics"
+	"llm-your-business/services/scheduler/internal/registry"
 	"llm-your-business/services/scheduler/internal/topics"
 )
 
 type Consumer struct {
-	readers  []*kafka.Reader
-	handlers *handlers.Handlers
+	broker   Broker
+	readers  []topicReader
+	registry *registry.Registry
+	producer *Producer // publishes to retry and dead-letter topics
+	retry    retryPolicy
+	workers  int         // handler goroutines per consume loop
+	running  sync.Map    // topic -> struct{} while its consume loop runs
+	inflight drain.Group // messages being handled
 }
 
-func NewConsumer(cfg *config.Config, h *handlers.Handlers) (*Consumer, error) {
-	if len(cfg.KafkaTopics) == 0 {
-		return nil, fmt.Errorf("no Kafka topics configured")
+// topicReader is a Reader together with the topic it reads.
+type topicReader struct {
+	Reader
+	topic string
+}
+
+// NewConsumer creates one reader on broker per topic registered in reg plus
+// one for each of the topic's retry topics, one per backoff tier. KAFKA_TOPICS, when set, narrows this to
+// the topics it lists. Failed dispatches are re-published via producer.
+func NewConsumer(cfg *config.Config, broker Broker, reg *registry.Registry, producer *Producer) (*Consumer, error) {
+	subscribed := reg.Topics()
+	if len(cfg.KafkaTopics) > 0 {
+		subscribed = subscribed[:0]
+		for _, topic := range cfg.KafkaTopics {
+			if !reg.Has(topic) {
+				return nil, fmt.Errorf("KAFKA_TOPICS: no handlers registered for %s", topic)
+			}
+			subscribed = append(subscribed, topic)
+		}
+	}
+	if len(subscribed) == 0 {
+		return nil, fmt.Errorf("no Kafka topics to consume")
 	}
 
-	readers := make([]*kafka.Reader, 0, len(cfg.KafkaTopics))
-
-	for _, topic := range cfg.KafkaTopics {
-		r := kafka.NewReader(kafka.ReaderConfig{
-			Brokers:               cfg.KafkaBrokers,
-			GroupID:               cfg.KafkaGroupID,
-			Topic:                 strings.TrimSpace(topic),
-			StartOffset:           kafka.LastOffset,
-			HeartbeatInterval:     0,
-			WatchPartitionChanges: true,
-			// Min/MaxBytes and other tuning can be added later
-		})
-		readers = append(readers, r)
+	retry := newRetryPolicy(cfg)
+	tiers := retry.tiers()
+	readers := make([]topicReader, 0, (1+len(tiers))*len(subscribed))
+	for _, topic := range subscribed {
+		ts := []string{topic}
+		for _, d := range tiers {
+			ts = append(ts, topics.Retry(topic, d))
+		}
+		for _, t := range ts {
+			r := broker.Reader(ReaderConfig{Topic: t, GroupID: cfg.KafkaGroupID})
+			readers = append(readers, topicReader{Reader: r, topic: t})
+		}
 	}
 
-	return &Consumer{readers: readers, handlers: h}, nil
+	return &Consumer{broker: broker, readers: readers, registry: reg, producer: producer, retry: retry, workers: cfg.KafkaWorkers}, nil
+}
+
+// Drain waits for the messages being handled to finish and be committed once
+// the context passed to Start is canceled, which stops fetching. When ctx is
+// done first, the handlers still running are canceled; their messages stay
+// uncommitted and are redelivered.
+func (c *Consumer) Drain(ctx context.Context) error {
+	return c.inflight.Wait(ctx)
 }
 
+// Close leaves the consumer groups. Call it after Drain.
 func (c *Consumer) Close(ctx context.Context) error {
 	var firstErr error
 	for _, r := range c.readers {
@@ -63,7 +98,7 @@ func (c *Consumer) Start(ctx context.Context) error {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if err := c.consumeLoop(ctx, reader); err != nil {
+			if err := c.consumeLoop(ctx, reader.topic, reader); err != nil {
 				errs <- err
 			}
 		}()
@@ -80,57 +115,134 @@ func (c *Consumer) Start(ctx context.Context) error {
 	}
 }
 
-func (c *Consumer) consumeLoop(ctx context.Context, r *kafka.Reader) error {
-	topic := r.Config().Topic
-	log.Printf("kafka consumer started: topic=%s", topic)
-	defer log.Printf("kafka consumer stopped: topic=%s", topic)
+// consumeLoop fetches messages and hands them to KAFKA_WORKERS workers, which
+// handle and then commit them. Messages with the same key (execution_id) go to
+// the same worker and are handled in fetch order; others run concurrently.
+// An offset is committed only once its message and every message fetched
+// before it from the same partition were handled (or handed off to a retry or
+// dead-letter topic). A crash mid-handler leaves the offset uncommitted and
+// the message is redelivered. Canceling ctx stops fetching; messages already
+// fetched are still handled and committed, unless Drain gives up on them.
+func (c *Consumer) consumeLoop(ctx context.Context, topic string, r Reader) error {
+	source, isRetry := topics.Source(topic)
+	offsets := newOffsetTracker()
+	ctx = logging.With(ctx, "topic", topic)
+	slog.InfoContext(ctx, "kafka consumer started", "workers", c.workers)
+	defer slog.InfoContext(ctx, "kafka consumer stopped")
+	c.running.Store(topic, struct{}{})
+	defer c.running.Delete(topic)
+
+	// A worker that can neither handle nor hand off a message stops the loop
+	// with that error.
+	fetchCtx, stopFetch := context.WithCancel(ctx)
+	defer stopFetch()
+	failErr := make(chan error, 1)
+	fail := func(err error) {
+		select {
+		case failErr <- err:
+		default:
+		}
+		stopFetch()
+	}
+	failed := func() error {
+		select {
+		case err := <-failErr:
+			return err
+		default:
+			return nil
+		}
+	}
+	workers := newKeyedWorkers(c.workers)
+	defer workers.close()
 
 	for {
-		m, err := r.ReadMessage(ctx)
+		m, err := r.FetchMessage(fetchCtx)
 		if err != nil {
+			if ferr := failed(); ferr != nil {
+				return ferr
+			}
 			if ctx.Err() != nil {
 				return nil
 			}
-			return fmt.Errorf("read message: %w", err)
+			metrics.ConsumerErrors.WithLabelValues(topic, "fetch").Inc()
+			return fmt.Errorf("fetch message: %w", err)
+		}
+		offsets.start(m)
+		if !m.Time.IsZero() {
+			metrics.ConsumeLatency.WithLabelValues(topic).Observe(time.Since(m.Time).Seconds())
 		}
 
-		if err := c.dispatch(ctx, topic, m.Value); err != nil {
-			// For now, log and continue; depending on requirements, consider DLQ
-			log.Printf("dispatch error: topic=%s err=%v", topic, err)
+		var failures int64
+		if isRetry {
+			failures = headerInt(m.Headers, HeaderAttempt)
+			if err := waitForRetry(fetchCtx, m); err != nil {
+				return failed() // not committed; redelivered on restart
+			}
+		}
+
+		work, done := c.inflight.Start(ctx)
+		queued := workers.submit(fetchCtx, m.Key, func() {
+			defer done()
+			if err := c.handle(work, topic, source, r, offsets, m, int(failures)+1); err != nil {
+				fail(err)
+			}
+		})
+		if !queued {
+			done()
+			return failed() // not committed; redelivered on restart
 		}
 	}
 }
 
-func (c *Consumer) dispatch(ctx context.Context, topic string, payload []byte) error {
-	switch topic {
-	case topics.TopicObjectiveExecutionQuestion:
-		var evt events.ObjectiveExecutionQuestionV1Json
-		if err := json.Unmarshal(payload, &evt); err != nil {
-			return fmt.Errorf("decode question: %w", err)
-		}
-		return c.handlers.HandleObjectiveExecutionQuestion(ctx, evt)
-	case topics.TopicObjectiveExecutionAnswer:
-		var evt events.ObjectiveExecutionAnswerV1Json
-		if err := json.Unmarshal(payload, &evt); err != nil {
-			return fmt.Errorf("decode answer: %w", err)
-		}
-		return c.handlers.HandleObjectiveExecutionAnswer(ctx, evt)
-	case topics.TopicObjectiveDatapoint:
-		var evt events.ObjectiveDatapointV1Json
-		if err := json.Unmarshal(payload, &evt); err != nil {
-			return fmt.Errorf("decode datapoint: %w", err)
+// handle dispatches m and commits its offset when that advances the commit
+// point. attempt is passed to handleFailure if the dispatch fails. It returns
+// an error only when m could neither be handled nor handed off.
+func (c *Consumer) handle(ctx context.Context, topic, source string, r Reader, offsets *offsetTracker, m kafka.Message, attempt int) error {
+	start := time.Now()
+	err := c.dispatch(ctx, source, m)
+	metrics.DispatchDuration.WithLabelValues(topic).Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.ConsumerErrors.WithLabelValues(topic, "dispatch").Inc()
+		if ferr := c.handleFailure(ctx, source, m, attempt, err); ferr != nil {
+			if ctx.Err() != nil {
+				return nil // canceled by Drain; redelivered on restart
+			}
+			// Neither handled nor handed off: stop without committing so
+			// the message is redelivered rather than lost.
+			return fmt.Errorf("dispatch error: topic=%s partition=%d offset=%d err=%v: %w", topic, m.Partition, m.Offset, err, ferr)
 		}
-		return c.handlers.HandleObjectiveDatapoint(ctx, evt)
-	case topics.TopicObjectiveManifest:
-		var evt events.ObjectiveManifestV1Json
-		if err := json.Unmarshal(payload, &evt); err != nil {
-			return fmt.Errorf("decode manifest: %w", err)
+	}
+
+	if commit, ok := offsets.done(m); ok {
+		if err := offsets.commit(ctx, r, commit); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			// The next successful commit covers this offset too.
+			metrics.ConsumerErrors.WithLabelValues(topic, "commit").Inc()
+			slog.ErrorContext(ctx, "commit error", "partition", commit.Partition, "offset", commit.Offset, "err", err)
 		}
-		return c.handlers.HandleObjectiveManifest(ctx, evt)
-	default:
-		// Unknown topic: try to identify by presence of fields if topic mapping is custom
-		// Fallback: just log and ignore
-		log.Printf("unknown topic; skipping: %s", topic)
-		return nil
 	}
+	return nil
+}
+
+// dispatch validates m and hands it to the registry, which decodes it into the
+// current event type whatever its schema_version and runs the topic's
+// handlers. It runs in a span that continues the trace from m's headers.
+// Payloads that do not match the topic's event schema fail with a
+// *validate.Error and are quarantined; undecodable ones are dead-lettered.
+func (c *Consumer) dispatch(ctx context.Context, topic string, m kafka.Message) (err error) {
+	ctx, span := startProcessSpan(ctx, topic, m)
+	defer func() { endSpan(span, err) }()
+
+	payload := m.Value
+	if err := c.producer.Validate(topic, payload); err != nil {
+		return err
+	}
+	err = c.registry.Dispatch(ctx, topic, payload)
+	var derr *registry.DecodeError
+	if errors.As(err, &derr) {
+		return permanent(err)
+	}
+	return err
 }
@@ -0,0 +1,28 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// Ping checks that a broker answers a metadata request.
+func (p *Producer) Ping(ctx context.Context) error {
+	return p.broker.Ping(ctx)
+}
+
+// Ping checks that every reader's consume loop is running and that the
+// metadata of the topics read can be read from the brokers, in one request
+// for all of them. Retry topics that have not been created yet are fine.
+func (c *Consumer) Ping(ctx context.Context) error {
+	topics := make([]string, 0, len(c.readers))
+	for _, r := range c.readers {
+		if _, ok := c.running.Load(r.topic); !ok {
+			return fmt.Errorf("consumer not running: topic=%s", r.topic)
+		}
+		if !slices.Contains(topics, r.topic) {
+			topics = append(topics, r.topic)
+		}
+	}
+	return c.broker.CheckTopics(ctx, topics...)
+}
@@ -0,0 +1,59 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"llm-your-business/services/scheduler/internal/config"
+	"llm-your-business/services/scheduler/internal/decode"
+	"llm-your-business/services/scheduler/internal/registry"
+)
+
+// checkBroker records the CheckTopics requests a readiness probe makes.
+type checkBroker struct {
+	Broker
+	requests [][]string
+	err      error
+}
+
+func (b *checkBroker) Reader(ReaderConfig) Reader { return &commitRecorder{} }
+
+func (b *checkBroker) CheckTopics(ctx context.Context, topics ...string) error {
+	b.requests = append(b.requests, topics)
+	return b.err
+}
+
+func TestConsumerPing(t *testing.T) {
+	reg := registry.New()
+	for _, topic := range []string{"a", "b"} {
+		registry.Subscribe(reg, decode.New[struct{}](topic, 1), "noop", func(context.Context, struct{}) error { return nil })
+	}
+	cfg := &config.Config{KafkaGroupID: "scheduler", RetryMaxAttempts: 3, RetryInitialBackoff: time.Second, RetryMaxBackoff: 4 * time.Second}
+	broker := &checkBroker{}
+	c, err := NewConsumer(cfg, broker, reg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Ping(context.Background()); err == nil {
+		t.Fatal("Ping passed with no consume loop running")
+	}
+	for _, r := range c.readers {
+		c.running.Store(r.topic, struct{}{})
+	}
+	if err := c.Ping(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"a", "a.retry.1s", "a.retry.2s", "a.retry.4s", "b", "b.retry.1s", "b.retry.2s", "b.retry.4s"}}
+	if !reflect.DeepEqual(broker.requests, want) {
+		t.Fatalf("probe made requests %v, want one for every topic %v", broker.requests, want)
+	}
+
+	broker.err = errors.New("leader not available")
+	if err := c.Ping(context.Background()); !errors.Is(err, broker.err) {
+		t.Fatalf("Ping = %v, want the broker's error", err)
+	}
+}
@@ -0,0 +1,344 @@
+// Package memory is an in-process kafka.Broker for tests and local runs.
+//
+// Like Kafka, it keeps every message of a topic in partitions chosen by key
+// hash, tracks committed offsets per consumer group and splits a topic's
+// partitions between the members of a group. When a member joins or leaves,
+// the others resume their partitions from the committed offsets, so messages
+// fetched but not committed are delivered again. Nothing is ever deleted.
+package memory
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	kafka "github.com/segmentio/kafka-go"
+
+	kafkapkg "llm-your-business/services/scheduler/internal/kafka"
+)
+
+// ErrClosed is returned by operations on a closed Reader or Writer.
+var ErrClosed = errors.New("memory broker: closed")
+
+// Broker holds topics and consumer groups in memory. It is safe for
+// concurrent use.
+type Broker struct {
+	partitions int
+
+	mu      sync.Mutex
+	changed chan struct{} // closed and replaced whenever messages or groups change
+	topics  map[string]*topic
+	groups  map[groupKey]*group
+}
+
+var _ kafkapkg.Broker = (*Broker)(nil)
+
+type topic struct {
+	parts [][]kafka.Message
+	next  int // round-robin partition for messages without a key
+}
+
+type groupKey struct {
+	topic string
+	id    string
+}
+
+type group struct {
+	committed  []int64 // next offset to deliver, per partition
+	members    []*Reader
+	generation int // bumped when members change
+}
+
+// New returns a broker whose topics have the given number of partitions
+// (at least one).
+func New(partitions int) *Broker {
+	if partitions < 1 {
+		partitions = 1
+	}
+	return &Broker{
+		partitions: partitions,
+		changed:    make(chan struct{}),
+		topics:     make(map[string]*topic),
+		groups:     make(map[groupKey]*group),
+	}
+}
+
+// topic returns the named topic, creating it on first use. Callers hold b.mu.
+func (b *Broker) topic(name string) *topic {
+	t := b.topics[name]
+	if t == nil {
+		t = &topic{parts: make([][]kafka.Message, b.partitions)}
+		b.topics[name] = t
+	}
+	return t
+}
+
+// notify wakes every waiting FetchMessage. Callers hold b.mu.
+func (b *Broker) notify() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// Messages returns every message written to topic, ordered by
+// partition and offset.
+func (b *Broker) Messages(name string) []kafka.Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t := b.topics[name]
+	if t == nil {
+		return nil
+	}
+	var out []kafka.Message
+	for _, p := range t.parts {
+		out = append(out, p...)
+	}
+	return out
+}
+
+// Topics returns the names of the topics written to or read from so far.
+func (b *Broker) Topics() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make([]string, 0, len(b.topics))
+	for name := range b.topics {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Lag returns how many messages of topic the group has not committed yet.
+func (b *Broker) Lag(topicName, groupID string) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t := b.topics[topicName]
+	g := b.groups[groupKey{topicName, groupID}]
+	if t == nil {
+		return 0
+	}
+	var lag int64
+	for p, msgs := range t.parts {
+		committed := int64(0)
+		if g != nil {
+			committed = g.committed[p]
+		}
+		lag += int64(len(msgs)) - committed
+	}
+	return lag
+}
+
+func (b *Broker) Ping(ctx context.Context) error { return nil }
+
+func (b *Broker) CheckTopics(ctx context.Context, topics ...string) error { return nil }
+
+func (b *Broker) Writer(topic string) kafkapkg.Writer {
+	return &Writer{broker: b, topic: topic}
+}
+
+// Writer appends messages to one topic of a Broker.
+type Writer struct {
+	broker *Broker
+	topic  string
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// WriteMessages appends msgs to the topic. A message goes to the partition
+// its key hashes to, or round-robin when it has no key.
+func (w *Writer) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	w.mu.Lock()
+	closed := w.closed
+	w.mu.Unlock()
+	if closed {
+		return ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b := w.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t := b.topic(w.topic)
+	now := time.Now()
+	for _, m := range msgs {
+		var p int
+		if len(m.Key) > 0 {
+			h := fnv.New32a()
+			h.Write(m.Key)
+			p = int(h.Sum32() % uint32(len(t.parts)))
+		} else {
+			p = t.next
+			t.next = (t.next + 1) % len(t.parts)
+		}
+		stored := kafka.Message{
+			Topic:     w.topic,
+			Partition: p,
+			Offset:    int64(len(t.parts[p])),
+			Key:       append([]byte(nil), m.Key...),
+			Value:     append([]byte(nil), m.Value...),
+			Headers:   append([]kafka.Header(nil), m.Headers...),
+			Time:      m.Time,
+		}
+		if stored.Time.IsZero() {
+			stored.Time = now
+		}
+		t.parts[p] = append(t.parts[p], stored)
+	}
+	b.notify()
+	return nil
+}
+
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	return nil
+}
+
+// Reader joins cfg.GroupID on cfg.Topic. A reader without a group gets a
+// group of its own.
+func (b *Broker) Reader(cfg kafkapkg.ReaderConfig) kafkapkg.Reader {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t := b.topic(cfg.Topic)
+	r := &Reader{broker: b, topic: cfg.Topic}
+	key := groupKey{cfg.Topic, cfg.GroupID}
+	g := b.groups[key]
+	if g == nil || cfg.GroupID == "" {
+		g = &group{committed: make([]int64, len(t.parts))}
+		if !cfg.FromStart {
+			for p, msgs := range t.parts {
+				g.committed[p] = int64(len(msgs))
+			}
+		}
+		if cfg.GroupID != "" {
+			b.groups[key] = g
+		}
+	}
+	r.group = g
+	g.members = append(g.members, r)
+	g.generation++
+	b.notify()
+	return r
+}
+
+// Reader consumes the partitions of one topic its group assigns to it.
+type Reader struct {
+	broker *Broker
+	topic  string
+	group  *group
+
+	// Guarded by broker.mu.
+	closed     bool
+	generation int           // group generation position was taken in
+	position   map[int]int64 // next offset to fetch per assigned partition
+	next       int           // partition to look at first, for fairness
+}
+
+// assigned returns the partitions of the topic this reader owns: members
+// take partitions round-robin in join order. Callers hold broker.mu.
+func (r *Reader) assigned(parts int) []int {
+	idx := -1
+	for i, m := range r.group.members {
+		if m == r {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return nil
+	}
+	var out []int
+	for p := idx; p < parts; p += len(r.group.members) {
+		out = append(out, p)
+	}
+	return out
+}
+
+// FetchMessage returns the next message of an assigned partition, blocking
+// until one is written or ctx is done. It does not commit.
+func (r *Reader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	b := r.broker
+	for {
+		b.mu.Lock()
+		if r.closed {
+			b.mu.Unlock()
+			return kafka.Message{}, ErrClosed
+		}
+		t := b.topics[r.topic]
+		if r.position == nil || r.generation != r.group.generation {
+			// Rebalanced: resume every partition from the group's commits.
+			r.generation = r.group.generation
+			r.position = make(map[int]int64)
+			for _, p := range r.assigned(len(t.parts)) {
+				r.position[p] = r.group.committed[p]
+			}
+		}
+		parts := r.assigned(len(t.parts))
+		for i := range parts {
+			p := parts[(r.next+i)%len(parts)]
+			off := r.position[p]
+			if off < int64(len(t.parts[p])) {
+				m := t.parts[p][off]
+				r.position[p] = off + 1
+				r.next = (r.next + i + 1) % len(parts)
+				b.mu.Unlock()
+				return m, nil
+			}
+		}
+		changed := b.changed
+		b.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return kafka.Message{}, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// CommitMessages marks msgs, and everything before them in their partitions,
+// as consumed by the group. Commits never move an offset backwards.
+func (r *Reader) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	b := r.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if r.closed {
+		return ErrClosed
+	}
+	for _, m := range msgs {
+		if m.Partition < 0 || m.Partition >= len(r.group.committed) {
+			continue
+		}
+		if next := m.Offset + 1; next > r.group.committed[m.Partition] {
+			r.group.committed[m.Partition] = next
+		}
+	}
+	return nil
+}
+
+// Close leaves the group; its partitions move to the remaining members.
+func (r *Reader) Close() error {
+	b := r.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	members := r.group.members[:0]
+	for _, m := range r.group.members {
+		if m != r {
+			members = append(members, m)
+		}
+	}
+	r.group.members = members
+	r.group.generation++
+	b.notify()
+	return nil
+}
@@ -0,0 +1,83 @@
+package kafka
+
+import (
+	"context"
+	"sync"
+
+	kafka "github.com/segmentio/kafka-go"
+)
+
+// offsetTracker decides which offsets are safe to commit. Messages are
+// registered in fetch order per partition; a partition's commit point only
+// advances over a contiguous prefix of finished messages, so an unfinished
+// message always holds back the commits of everything fetched after it.
+type offsetTracker struct {
+	mu         sync.Mutex
+	partitions map[int]*partitionOffsets
+
+	commitMu  sync.Mutex
+	committed map[int]int64 // highest offset committed per partition
+}
+
+type partitionOffsets struct {
+	inflight []int64                 // fetched offsets, in fetch order
+	done     map[int64]kafka.Message // finished but not yet committable
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{partitions: make(map[int]*partitionOffsets), committed: make(map[int]int64)}
+}
+
+// start registers a fetched message as in flight.
+func (t *offsetTracker) start(m kafka.Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p := t.partitions[m.Partition]
+	if p == nil {
+		p = &partitionOffsets{done: make(map[int64]kafka.Message)}
+		t.partitions[m.Partition] = p
+	}
+	p.inflight = append(p.inflight, m.Offset)
+}
+
+// done marks a message as finished and returns the message whose offset
+// should be committed, if the partition's commit point advanced.
+func (t *offsetTracker) done(m kafka.Message) (kafka.Message, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p := t.partitions[m.Partition]
+	if p == nil {
+		return kafka.Message{}, false
+	}
+	p.done[m.Offset] = m
+
+	var commit kafka.Message
+	advanced := false
+	for len(p.inflight) > 0 {
+		fm, ok := p.done[p.inflight[0]]
+		if !ok {
+			break
+		}
+		delete(p.done, p.inflight[0])
+		p.inflight = p.inflight[1:]
+		commit, advanced = fm, true
+	}
+	return commit, advanced
+}
+
+// commit commits m's offset through r unless a later offset of its partition
+// was committed already. Workers finish messages out of order and may reach
+// commit in a different order than done returned their offsets, so commits
+// are serialized here to never move a partition's commit point backwards.
+func (t *offsetTracker) commit(ctx context.Context, r Reader, m kafka.Message) error {
+	t.commitMu.Lock()
+	defer t.commitMu.Unlock()
+	if last, ok := t.committed[m.Partition]; ok && last >= m.Offset {
+		return nil
+	}
+	if err := r.CommitMessages(ctx, m); err != nil {
+		return err
+	}
+	t.committed[m.Partition] = m.Offset
+	return nil
+}
@@ -0,0 +1,157 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	kafka "github.com/segmentio/kafka-go"
+)
+
+// commitRecorder is a Reader that records the offsets committed through it.
+type commitRecorder struct {
+	mu        sync.Mutex
+	committed []int64
+	err       error // returned by the next CommitMessages, then cleared
+}
+
+func (r *commitRecorder) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	<-ctx.Done()
+	return kafka.Message{}, ctx.Err()
+}
+
+func (r *commitRecorder) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.err; err != nil {
+		r.err = nil
+		return err
+	}
+	for _, m := range msgs {
+		r.committed = append(r.committed, m.Offset)
+	}
+	return nil
+}
+
+func (r *commitRecorder) Close() error { return nil }
+
+func (r *commitRecorder) offsets() []int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]int64(nil), r.committed...)
+}
+
+func TestOffsetTrackerDone(t *testing.T) {
+	// A step starts or finishes the message at offset in partition 0, or in
+	// partition 1 when p1 is set. want is the offset done returns to commit,
+	// or -1 when the commit point does not advance.
+	type step struct {
+		start  bool
+		offset int64
+		p1     bool
+		want   int64
+	}
+	start := func(offset int64) step { return step{start: true, offset: offset} }
+	done := func(offset, want int64) step { return step{offset: offset, want: want} }
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name:  "in order",
+			steps: []step{start(0), start(1), start(2), done(0, 0), done(1, 1), done(2, 2)},
+		},
+		{
+			name:  "out of order",
+			steps: []step{start(0), start(1), start(2), done(2, -1), done(1, -1), done(0, 2)},
+		},
+		{
+			name:  "gap filled in the middle",
+			steps: []step{start(0), start(1), start(2), start(3), done(0, 0), done(2, -1), done(3, -1), done(1, 3)},
+		},
+		{
+			name:  "offsets need not be contiguous",
+			steps: []step{start(4), start(9), start(10), done(9, -1), done(4, 9), done(10, 10)},
+		},
+		{
+			name: "partitions advance independently",
+			steps: []step{
+				start(0), {start: true, offset: 0, p1: true}, start(1),
+				{offset: 0, p1: true, want: 0}, done(1, -1), done(0, 1),
+			},
+		},
+		{
+			name:  "redelivered before the first delivery finished",
+			steps: []step{start(5), start(6), start(5), done(5, 5), done(6, 6), done(5, 5)},
+		},
+		{
+			name:  "redelivered after a later offset finished",
+			steps: []step{start(5), start(6), done(6, -1), start(5), done(5, 6), done(5, 5)},
+		},
+		{
+			name:  "done without start",
+			steps: []step{done(3, -1), start(4), done(4, 4)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := newOffsetTracker()
+			for i, s := range tt.steps {
+				m := kafka.Message{Offset: s.offset}
+				if s.p1 {
+					m.Partition = 1
+				}
+				if s.start {
+					tr.start(m)
+					continue
+				}
+				got, ok := tr.done(m)
+				switch {
+				case s.want < 0 && ok:
+					t.Fatalf("step %d: done(%d) committed %d, want nothing", i, s.offset, got.Offset)
+				case s.want >= 0 && (!ok || got.Offset != s.want || got.Partition != m.Partition):
+					t.Fatalf("step %d: done(%d) = %d/%d, %v, want %d/%d", i, s.offset, got.Partition, got.Offset, ok, m.Partition, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestOffsetTrackerCommit(t *testing.T) {
+	errCommit := errors.New("broker unavailable")
+	type step struct {
+		partition int
+		offset    int64
+		fail      bool // the reader fails this commit
+	}
+	tests := []struct {
+		name  string
+		steps []step
+		want  []int64 // offsets committed through the reader
+	}{
+		{name: "forward", steps: []step{{0, 1, false}, {0, 3, false}}, want: []int64{1, 3}},
+		{name: "stale commit skipped", steps: []step{{0, 3, false}, {0, 1, false}, {0, 3, false}}, want: []int64{3}},
+		{name: "per partition", steps: []step{{0, 3, false}, {1, 1, false}, {1, 2, false}}, want: []int64{3, 1, 2}},
+		{name: "failed commit retried by the next one", steps: []step{{0, 1, true}, {0, 1, false}, {0, 2, false}}, want: []int64{1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := newOffsetTracker()
+			r := &commitRecorder{}
+			for _, s := range tt.steps {
+				if s.fail {
+					r.err = errCommit
+				}
+				err := tr.commit(context.Background(), r, kafka.Message{Partition: s.partition, Offset: s.offset})
+				if s.fail != (err != nil) {
+					t.Fatalf("commit(%d/%d) = %v", s.partition, s.offset, err)
+				}
+			}
+			if got := r.offsets(); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("committed %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -10,32 +10,33 @@ import (
     kafka "github.com/segmentio/kafka-go"
 
     "llm-your-business/schemas/events"
-    "llm-your-business/services/scheduler/internal/config"
+    "llm-your-business/schemas/validate"
+    "llm-your-business/services/scheduler/internal/metrics"
     "llm-your-business/services/scheduler/internal/topics"
 )
 
 type Producer struct {
-	brokers []string
-	dialer  *kafka.Dialer
+	broker    Broker
+	validator *validate.Validator
 
 	mu      sync.RWMutex
-	writers map[string]*kafka.Writer
+	writers map[string]Writer
 }
 
-func NewProducer(cfg *config.Config) (*Producer, error) {
-	d := &kafka.Dialer{
-		Timeout:   10 * time.Second,
-		DualStack: true,
-		ClientID:  cfg.KafkaClientID,
+// NewProducer creates a producer publishing through broker.
+func NewProducer(broker Broker) (*Producer, error) {
+	v, err := validate.New()
+	if err != nil {
+		return nil, fmt.Errorf("load event schemas: %w", err)
 	}
 	return &Producer{
-		brokers: cfg.KafkaBrokers,
-		dialer:  d,
-		writers: make(map[string]*kafka.Writer),
+		broker:    broker,
+		validator: v,
+		writers:   make(map[string]Writer),
 	}, nil
 }
 
-func (p *Producer) getWriter(topic string) *kafka.Writer {
+func (p *Producer) getWriter(topic string) Writer {
 	p.mu.RLock()
 	w := p.writers[topic]
 	p.mu.RUnlock()
@@ -48,22 +49,46 @@ func (p *Producer) getWriter(topic string) *kafka.Writer {
 	if w = p.writers[topic]; w != nil {
 		return w
 	}
-	w = &kafka.Writer{
-		Addr:         kafka.TCP(p.brokers...),
-		Topic:        topic,
-		Balancer:     &kafka.LeastBytes{},
-		RequiredAcks: kafka.RequireAll,
-		Async:        false,
-		BatchTimeout: 50 * time.Millisecond,
-	}
+	w = p.broker.Writer(topic)
 	p.writers[topic] = w
 	return w
 }
 
 func (p *Producer) Publish(ctx context.Context, topic string, key, value []byte) error {
+    return p.PublishWithHeaders(ctx, topic, key, value, nil)
+}
+
+// PublishWithHeaders is like Publish but attaches Kafka message headers. The
+// W3C trace context of a publish span is added to them. Payloads for topics
+// with an event schema are refused with a *validate.Error when invalid.
+func (p *Producer) PublishWithHeaders(ctx context.Context, topic string, key, value []byte, headers []kafka.Header) (err error) {
+    headers = append([]kafka.Header(nil), headers...)
+    ctx, span := startPublishSpan(ctx, topic, &headers)
+    defer func() { endSpan(span, err) }()
+
+    if err := p.Validate(topic, value); err != nil {
+        metrics.PublishFailures.WithLabelValues(topic).Inc()
+        return fmt.Errorf("refusing to publish to %s: %w", topic, err)
+    }
+
     w := p.getWriter(topic)
-    msg := kafka.Message{Key: key, Value: value, Time: time.Now()}
-    return w.WriteMessages(ctx, msg)
+    msg := kafka.Message{Key: key, Value: value, Headers: headers, Time: time.Now()}
+    start := time.Now()
+    err = w.WriteMessages(ctx, msg)
+    metrics.PublishDuration.WithLabelValues(topic).Observe(time.Since(start).Seconds())
+    if err != nil {
+        metrics.PublishFailures.WithLabelValues(topic).Inc()
+    }
+    return err
+}
+
+// Validate checks payload against the event schema of topic. Topics without
+// a schema, such as retry and dead-letter topics, accept any payload.
+func (p *Producer) Validate(topic string, payload []byte) error {
+    if !p.validator.Has(topic) {
+        return nil
+    }
+    return p.validator.Validate(topic, payload)
 }
 
 // PublishObjectiveManifest marshals and publishes an ObjectiveManifest event
@@ -94,6 +119,17 @@ func (p *Producer) PublishObjectiveExecutionQuestion(ctx context.Context, evt ev
     return p.Publish(ctx, topics.TopicObjectiveExecutionQuestion, key, payload)
 }
 
+// PublishObjectiveDatapoint marshals and publishes an ObjectiveDatapoint
+// event to the appropriate Kafka topic.
+func (p *Producer) PublishObjectiveDatapoint(ctx context.Context, evt events.ObjectiveDatapointV1Json) error {
+    payload, err := json.Marshal(evt)
+    if err != nil {
+        return fmt.Errorf("marshal ObjectiveDatapoint: %w", err)
+    }
+    key := []byte(evt.Meta.ExecutionId)
+    return p.Publish(ctx, topics.TopicObjectiveDatapoint, key, payload)
+}
+
 func (p *Producer) Close(ctx context.Context) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -0,0 +1,62 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	kafka "github.com/segmentio/kafka-go"
+
+	"llm-your-business/services/scheduler/internal/config"
+	"llm-your-business/services/scheduler/internal/topics"
+)
+
+// HeaderRedriveCount counts how many times a message was re-driven from its
+// dead-letter topic, so operators can spot messages that keep bouncing.
+const HeaderRedriveCount = "x-redrive-count"
+
+// redriveIdle is how long Redrive waits for another DLQ message before it
+// assumes the topic is drained.
+const redriveIdle = 5 * time.Second
+
+// Redrive moves messages from the dead-letter topic of source back onto
+// source, up to limit messages (0 means all). Each message is committed on
+// the DLQ only after it was re-published. Returns the number moved.
+func Redrive(ctx context.Context, cfg *config.Config, p *Producer, source string, limit int) (int, error) {
+	dlq := topics.DeadLetter(source)
+	r := p.broker.Reader(ReaderConfig{Topic: dlq, GroupID: cfg.KafkaGroupID + "-redrive", FromStart: true})
+	defer r.Close()
+
+	moved := 0
+	for limit <= 0 || moved < limit {
+		fetchCtx, cancel := context.WithTimeout(ctx, redriveIdle)
+		m, err := r.FetchMessage(fetchCtx)
+		cancel()
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+				break // drained
+			}
+			return moved, fmt.Errorf("fetch from %s: %w", dlq, err)
+		}
+
+		// The message keeps its trace context; the count replaces the carried one.
+		headers := []kafka.Header{{Key: HeaderRedriveCount, Value: []byte(strconv.FormatInt(headerInt(m.Headers, HeaderRedriveCount)+1, 10))}}
+		for _, h := range carriedHeaders(m.Headers) {
+			if h.Key != HeaderRedriveCount {
+				headers = append(headers, h)
+			}
+		}
+		if err := p.PublishWithHeaders(ctx, source, m.Key, m.Value, headers); err != nil {
+			return moved, fmt.Errorf("publish to %s: %w", source, err)
+		}
+		if err := r.CommitMessages(ctx, m); err != nil {
+			return moved, fmt.Errorf("commit %s offset %d: %w", dlq, m.Offset, err)
+		}
+		slog.InfoContext(ctx, "redrove message", "topic", dlq, "partition", m.Partition, "offset", m.Offset, "original_error", headerValue(m.Headers, HeaderError))
+		moved++
+	}
+	return moved, nil
+}
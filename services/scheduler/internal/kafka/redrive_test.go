@@ -0,0 +1,91 @@
+package kafka_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	kafka "github.com/segmentio/kafka-go"
+
+	"llm-your-business/services/scheduler/internal/config"
+	"llm-your-business/services/scheduler/internal/decode"
+	kafkapkg "llm-your-business/services/scheduler/internal/kafka"
+	"llm-your-business/services/scheduler/internal/kafka/memory"
+	"llm-your-business/services/scheduler/internal/registry"
+	"llm-your-business/services/scheduler/internal/topics"
+)
+
+// TestRedriveCountsBounces sends a message whose handler always fails
+// through DLQ -> redrive -> fail -> DLQ -> redrive and checks each copy
+// carries the redrive count and trace context of the one before.
+func TestRedriveCountsBounces(t *testing.T) {
+	const source = "test.bounce"
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	cfg := &config.Config{KafkaGroupID: "scheduler", KafkaWorkers: 1, RetryMaxAttempts: 0}
+	broker := memory.New(1)
+	producer, err := kafkapkg.NewProducer(broker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := registry.New()
+	registry.Subscribe(reg, decode.New[struct{}](source, 1), "fail", func(context.Context, struct{}) error {
+		return errors.New("still broken")
+	})
+	consumer, err := kafkapkg.NewConsumer(cfg, broker, reg, producer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer consumer.Close(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	go consumer.Start(ctx)
+
+	dlq := topics.DeadLetter(source)
+	waitDLQ := func(n int) kafka.Message {
+		t.Helper()
+		for len(broker.Messages(dlq)) < n {
+			select {
+			case <-ctx.Done():
+				t.Fatalf("waiting for dead-letter message %d: %v", n, ctx.Err())
+			case <-time.After(5 * time.Millisecond):
+			}
+		}
+		return broker.Messages(dlq)[n-1]
+	}
+	header := func(m kafka.Message, key string) string {
+		for _, h := range m.Headers {
+			if h.Key == key {
+				return string(h.Value)
+			}
+		}
+		return ""
+	}
+
+	payload := []byte(`{"meta":{"schema_version":1}}`)
+	headers := []kafka.Header{{Key: "traceparent", Value: []byte(traceparent)}}
+	if err := producer.PublishWithHeaders(ctx, source, []byte("e-1"), payload, headers); err != nil {
+		t.Fatal(err)
+	}
+	for bounce := 1; bounce <= 2; bounce++ {
+		m := waitDLQ(bounce)
+		if got, want := header(m, kafkapkg.HeaderRedriveCount), []string{"", "1"}[bounce-1]; got != want {
+			t.Fatalf("dead-letter copy %d has redrive count %q, want %q", bounce, got, want)
+		}
+		if got := header(m, "traceparent"); got != traceparent {
+			t.Fatalf("dead-letter copy %d has traceparent %q", bounce, got)
+		}
+		if n, err := kafkapkg.Redrive(ctx, cfg, producer, source, 1); err != nil || n != 1 {
+			t.Fatalf("redrive %d moved %d: %v", bounce, n, err)
+		}
+	}
+
+	msgs := broker.Messages(source)
+	last := msgs[len(msgs)-1]
+	if got := header(last, kafkapkg.HeaderRedriveCount); got != "2" {
+		t.Fatalf("second redrive wrote count %q, want 2", got)
+	}
+	if got := header(last, "traceparent"); got != traceparent {
+		t.Fatalf("redriven message has traceparent %q", got)
+	}
+}
@@ -0,0 +1,202 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	kafka "github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+
+	"llm-your-business/schemas/validate"
+	"llm-your-business/services/scheduler/internal/config"
+	"llm-your-business/services/scheduler/internal/metrics"
+	"llm-your-business/services/scheduler/internal/topics"
+)
+
+// Headers attached to messages on retry, dead-letter and quarantine topics.
+const (
+	HeaderOriginalTopic     = "x-original-topic"
+	HeaderOriginalPartition = "x-original-partition"
+	HeaderOriginalOffset    = "x-original-offset"
+	HeaderAttempt           = "x-attempt"
+	HeaderRetryAt           = "x-retry-at" // epoch millis
+	HeaderError             = "x-error"
+	HeaderValidationErrors  = "x-validation-errors" // JSON array of strings
+)
+
+// permanentError marks failures that retrying cannot fix (e.g. undecodable
+// payloads); such messages go straight to the dead-letter topic.
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+func permanent(err error) error { return &permanentError{err: err} }
+
+func isPermanent(err error) bool {
+	var pe *permanentError
+	return errors.As(err, &pe)
+}
+
+type retryPolicy struct {
+	maxAttempts int
+	initial     time.Duration
+	max         time.Duration
+}
+
+func newRetryPolicy(cfg *config.Config) retryPolicy {
+	return retryPolicy{maxAttempts: cfg.RetryMaxAttempts, initial: cfg.RetryInitialBackoff, max: cfg.RetryMaxBackoff}
+}
+
+// backoff returns the delay before the given retry attempt (1-based),
+// doubling from the initial backoff up to the configured maximum.
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	d := p.initial
+	for i := 1; i < attempt && d < p.max; i++ {
+		d *= 2
+	}
+	if d > p.max {
+		d = p.max
+	}
+	return d
+}
+
+// tiers returns the distinct delays backoff gives for attempts 1 to
+// maxAttempts, shortest first. Each has its own retry topic.
+func (p retryPolicy) tiers() []time.Duration {
+	var out []time.Duration
+	for attempt := 1; attempt <= p.maxAttempts; attempt++ {
+		if d := p.backoff(attempt); len(out) == 0 || d != out[len(out)-1] {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// handleFailure routes a message whose dispatch failed. attempt counts the
+// failures so far, including this one. Returns an error only when the message
+// could not be handed off to either the retry or the dead-letter topic.
+func (c *Consumer) handleFailure(ctx context.Context, source string, m kafka.Message, attempt int, cause error) error {
+	// Retry and dead-letter copies stay in the trace of the original message.
+	ctx = otel.GetTextMapPropagator().Extract(ctx, headerCarrier{&m.Headers})
+	var verr *validate.Error
+	if errors.As(cause, &verr) {
+		return c.quarantine(ctx, source, m, attempt, verr)
+	}
+	if isPermanent(cause) || attempt > c.retry.maxAttempts {
+		dlq := topics.DeadLetter(source)
+		if err := c.producer.PublishWithHeaders(ctx, dlq, m.Key, m.Value, failureHeaders(source, m, attempt, cause)); err != nil {
+			return fmt.Errorf("publish to %s: %w", dlq, err)
+		}
+		slog.WarnContext(ctx, "message dead-lettered", "source_topic", source, "attempts", attempt, "err", cause)
+		return nil
+	}
+
+	delay := c.retry.backoff(attempt)
+	headers := append(failureHeaders(source, m, attempt, cause),
+		kafka.Header{Key: HeaderRetryAt, Value: []byte(strconv.FormatInt(time.Now().Add(delay).UnixMilli(), 10))})
+	retryTopic := topics.Retry(source, delay)
+	if err := c.producer.PublishWithHeaders(ctx, retryTopic, m.Key, m.Value, headers); err != nil {
+		return fmt.Errorf("publish to %s: %w", retryTopic, err)
+	}
+	slog.WarnContext(ctx, "message scheduled for retry", "source_topic", source, "attempt", attempt, "delay", delay.String(), "err", cause)
+	return nil
+}
+
+// quarantine hands a message that does not match its event schema to the
+// source topic's quarantine topic, with the schema violations attached.
+// Retrying cannot fix it, and keeping it apart from the dead-letter topic
+// lets producers' contract breaks be told from handler failures.
+func (c *Consumer) quarantine(ctx context.Context, source string, m kafka.Message, attempt int, verr *validate.Error) error {
+	problems, err := json.Marshal(verr.Problems)
+	if err != nil {
+		return fmt.Errorf("marshal validation errors: %w", err)
+	}
+	headers := append(failureHeaders(source, m, attempt, verr),
+		kafka.Header{Key: HeaderValidationErrors, Value: problems})
+	qt := topics.Quarantine(source)
+	if err := c.producer.PublishWithHeaders(ctx, qt, m.Key, m.Value, headers); err != nil {
+		return fmt.Errorf("publish to %s: %w", qt, err)
+	}
+	metrics.MessagesQuarantined.WithLabelValues(source).Inc()
+	slog.WarnContext(ctx, "message quarantined", "source_topic", source, "problems", verr.Problems)
+	return nil
+}
+
+// failureHeaders describes where a message came from and why it failed. For
+// messages already on a retry topic the original coordinates are carried over,
+// and so are the redrive count and trace context of m.
+func failureHeaders(source string, m kafka.Message, attempt int, cause error) []kafka.Header {
+	partition := headerValue(m.Headers, HeaderOriginalPartition)
+	if partition == "" {
+		partition = strconv.Itoa(m.Partition)
+	}
+	offset := headerValue(m.Headers, HeaderOriginalOffset)
+	if offset == "" {
+		offset = strconv.FormatInt(m.Offset, 10)
+	}
+	return append(carriedHeaders(m.Headers),
+		kafka.Header{Key: HeaderOriginalTopic, Value: []byte(source)},
+		kafka.Header{Key: HeaderOriginalPartition, Value: []byte(partition)},
+		kafka.Header{Key: HeaderOriginalOffset, Value: []byte(offset)},
+		kafka.Header{Key: HeaderAttempt, Value: []byte(strconv.Itoa(attempt))},
+		kafka.Header{Key: HeaderError, Value: []byte(cause.Error())},
+	)
+}
+
+// carriedKeys are the headers a message keeps on every copy made of it on
+// retry, dead-letter, quarantine and redrive.
+var carriedKeys = []string{HeaderRedriveCount, "traceparent", "tracestate"}
+
+// carriedHeaders returns the headers of carriedKeys that headers has.
+func carriedHeaders(headers []kafka.Header) []kafka.Header {
+	var out []kafka.Header
+	for _, key := range carriedKeys {
+		if v := headerValue(headers, key); v != "" {
+			out = append(out, kafka.Header{Key: key, Value: []byte(v)})
+		}
+	}
+	return out
+}
+
+func headerValue(headers []kafka.Header, key string) string {
+	for _, h := range headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func headerInt(headers []kafka.Header, key string) int64 {
+	n, _ := strconv.ParseInt(headerValue(headers, key), 10, 64)
+	return n
+}
+
+// waitForRetry blocks until the message's x-retry-at time, or ctx is done.
+// Messages on one retry topic all wait the same delay, so their x-retry-at
+// times follow their offsets and blocking the topic's fetch loop on the
+// oldest holds back only messages that are not due yet either.
+func waitForRetry(ctx context.Context, m kafka.Message) error {
+	at := headerInt(m.Headers, HeaderRetryAt)
+	if at <= 0 {
+		return nil
+	}
+	d := time.Until(time.UnixMilli(at))
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
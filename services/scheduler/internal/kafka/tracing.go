@@ -0,0 +1,75 @@
+package kafka
+
+import (
+	"context"
+	"strconv"
+
+	kafka "github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("llm-your-business/services/scheduler/internal/kafka")
+
+// headerCarrier adapts Kafka message headers to the OpenTelemetry propagator,
+// which reads and writes W3C traceparent/tracestate through it.
+type headerCarrier struct{ headers *[]kafka.Header }
+
+func (c headerCarrier) Get(key string) string { return headerValue(*c.headers, key) }
+
+func (c headerCarrier) Set(key, value string) {
+	for i, h := range *c.headers {
+		if h.Key == key {
+			(*c.headers)[i].Value = []byte(value)
+			return
+		}
+	}
+	*c.headers = append(*c.headers, kafka.Header{Key: key, Value: []byte(value)})
+}
+
+func (c headerCarrier) Keys() []string {
+	keys := make([]string, len(*c.headers))
+	for i, h := range *c.headers {
+		keys[i] = h.Key
+	}
+	return keys
+}
+
+// startPublishSpan starts a producer span for a message to topic and injects
+// its context into headers.
+func startPublishSpan(ctx context.Context, topic string, headers *[]kafka.Header) (context.Context, trace.Span) {
+	ctx, span := tracer.Start(ctx, "publish "+topic,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingOperationTypePublish,
+			semconv.MessagingDestinationName(topic),
+		))
+	otel.GetTextMapPropagator().Inject(ctx, headerCarrier{headers})
+	return ctx, span
+}
+
+// startProcessSpan continues the trace carried in m's headers with a consumer
+// span for handling m.
+func startProcessSpan(ctx context.Context, source string, m kafka.Message) (context.Context, trace.Span) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, headerCarrier{&m.Headers})
+	return tracer.Start(ctx, "process "+source,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingOperationTypeDeliver,
+			semconv.MessagingDestinationName(m.Topic),
+			semconv.MessagingDestinationPartitionID(strconv.Itoa(m.Partition)),
+			semconv.MessagingKafkaMessageOffset(int(m.Offset)),
+		))
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,70 @@
+package kafka
+
+import (
+	"context"
+	"hash/fnv"
+)
+
+// workerQueue is how many messages may wait for each worker. When a worker's
+// queue is full the consume loop stops fetching until it has room.
+const workerQueue = 8
+
+// keyedWorkers runs jobs on a fixed set of goroutines. Jobs with the same key
+// run on the same goroutine in the order they were submitted, so the messages
+// of one execution are handled in order; jobs without a key are spread
+// round-robin.
+type keyedWorkers struct {
+	queues []chan func()
+	next   int // round-robin worker for jobs without a key
+}
+
+// newKeyedWorkers starts n workers (at least one).
+func newKeyedWorkers(n int) *keyedWorkers {
+	if n < 1 {
+		n = 1
+	}
+	w := &keyedWorkers{queues: make([]chan func(), n)}
+	for i := range w.queues {
+		q := make(chan func(), workerQueue)
+		w.queues[i] = q
+		go func() {
+			for job := range q {
+				job()
+			}
+		}()
+	}
+	return w
+}
+
+// submit queues job on the worker key maps to. It returns false, without
+// queuing the job, when ctx is done before the worker has room.
+func (w *keyedWorkers) submit(ctx context.Context, key []byte, job func()) bool {
+	var i int
+	if len(key) > 0 {
+		i = w.worker(key)
+	} else {
+		i = w.next
+		w.next = (w.next + 1) % len(w.queues)
+	}
+	select {
+	case w.queues[i] <- job:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// worker returns the index of the worker that runs the jobs of key.
+func (w *keyedWorkers) worker(key []byte) int {
+	h := fnv.New32a()
+	h.Write(key)
+	return int(h.Sum32() % uint32(len(w.queues)))
+}
+
+// close stops the workers once they have run the jobs already queued.
+// submit must not be called afterwards.
+func (w *keyedWorkers) close() {
+	for _, q := range w.queues {
+		close(q)
+	}
+}
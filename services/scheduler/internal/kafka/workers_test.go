@@ -0,0 +1,131 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	kafka "github.com/segmentio/kafka-go"
+)
+
+func TestKeyedWorkersOrder(t *testing.T) {
+	w := newKeyedWorkers(4)
+	defer w.close()
+	const keys, jobs = 8, 50
+
+	var mu sync.Mutex
+	ran := make(map[string][]int)
+	var wg sync.WaitGroup
+	for i := 0; i < jobs; i++ {
+		for k := 0; k < keys; k++ {
+			key := fmt.Sprintf("execution-%d", k)
+			wg.Add(1)
+			if !w.submit(context.Background(), []byte(key), func() {
+				defer wg.Done()
+				mu.Lock()
+				ran[key] = append(ran[key], i)
+				mu.Unlock()
+			}) {
+				t.Fatal("submit refused a job")
+			}
+		}
+	}
+	wg.Wait()
+
+	want := make([]int, jobs)
+	for i := range want {
+		want[i] = i
+	}
+	for k := 0; k < keys; k++ {
+		key := fmt.Sprintf("execution-%d", k)
+		if !reflect.DeepEqual(ran[key], want) {
+			t.Fatalf("jobs of %s ran in order %v", key, ran[key])
+		}
+	}
+}
+
+func TestKeyedWorkersSubmitCanceled(t *testing.T) {
+	w := newKeyedWorkers(1)
+	defer w.close()
+	release := make(chan struct{})
+	defer close(release)
+
+	// The running job and a full queue leave no room for another job.
+	for i := 0; i < workerQueue+1; i++ {
+		if !w.submit(context.Background(), []byte("k"), func() { <-release }) {
+			t.Fatal("submit refused a job with room")
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if w.submit(ctx, []byte("k"), func() { t.Error("a refused job ran") }) {
+		t.Fatal("submit queued a job without room")
+	}
+}
+
+// TestBlockedKeyHoldsBackCommit runs one partition's messages the way the
+// consume loop does: a message whose handler is blocked keeps the commit
+// point below its offset while messages of other keys after it finish.
+func TestBlockedKeyHoldsBackCommit(t *testing.T) {
+	w := newKeyedWorkers(2)
+	defer w.close()
+	tr := newOffsetTracker()
+	r := &commitRecorder{}
+
+	blocked := []byte("blocked")
+	var other []byte
+	for i := 0; other == nil; i++ {
+		if k := []byte(fmt.Sprintf("other-%d", i)); w.worker(k) != w.worker(blocked) {
+			other = k
+		}
+	}
+
+	// Offset 2 is blocked; the rest run on the other worker.
+	const n = 6
+	release := make(chan struct{})
+	var wg, othersDone sync.WaitGroup
+	for off := int64(0); off < n; off++ {
+		m := kafka.Message{Partition: 0, Offset: off, Key: other}
+		if off == 2 {
+			m.Key = blocked
+		} else if off > 2 {
+			othersDone.Add(1)
+		}
+		tr.start(m)
+		wg.Add(1)
+		w.submit(context.Background(), m.Key, func() {
+			defer wg.Done()
+			if off == 2 {
+				<-release
+			}
+			if commit, ok := tr.done(m); ok {
+				if err := tr.commit(context.Background(), r, commit); err != nil {
+					t.Error(err)
+				}
+			}
+			if off > 2 {
+				othersDone.Done()
+			}
+		})
+	}
+
+	othersDone.Wait()
+	if got := r.offsets(); !reflect.DeepEqual(got, []int64{0, 1}) {
+		t.Fatalf("with offset 2 blocked, committed %v, want [0 1]", got)
+	}
+
+	close(release)
+	wg.Wait()
+	got := r.offsets()
+	if last := got[len(got)-1]; last != n-1 {
+		t.Fatalf("after unblocking, committed %v, want it to end at %d", got, n-1)
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i] <= got[i-1] {
+			t.Fatalf("commit point moved backwards: %v", got)
+		}
+	}
+}
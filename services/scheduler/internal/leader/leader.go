@@ -0,0 +1,110 @@
+// Package leader elects a single active scheduler replica using a
+// MongoDB-backed lease.
+package leader
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"llm-your-business/services/scheduler/internal/db"
+)
+
+// Elector competes for a named lease and keeps renewing it while it leads.
+// A replica that stops renewing (crash, network partition) loses the lease
+// once it expires and another replica takes over on its next attempt.
+type Elector struct {
+	db      db.Leases
+	name    string
+	id      string
+	ttl     time.Duration
+	leading atomic.Bool
+	elected chan struct{}
+}
+
+// New creates an elector for the lease name. The lease is renewed every
+// ttl/3, so a leader can miss two renewals before it is replaced.
+func New(leases db.Leases, name string, ttl time.Duration) *Elector {
+	return &Elector{db: leases, name: name, id: instanceID(), ttl: ttl, elected: make(chan struct{}, 1)}
+}
+
+// ID identifies this replica as a lease holder.
+func (e *Elector) ID() string { return e.id }
+
+// IsLeader reports whether this replica currently holds the lease.
+func (e *Elector) IsLeader() bool { return e.leading.Load() }
+
+// Elected receives a value each time this replica becomes leader.
+func (e *Elector) Elected() <-chan struct{} { return e.elected }
+
+// Run acquires and renews the lease until ctx is canceled, then releases it.
+func (e *Elector) Run(ctx context.Context) error {
+	ticker := time.NewTicker(e.ttl / 3)
+	defer ticker.Stop()
+	defer e.release()
+
+	for {
+		e.attempt(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Elector) attempt(ctx context.Context) {
+	reqCtx, cancel := context.WithTimeout(ctx, e.ttl/3)
+	ok, err := e.db.AcquireLease(reqCtx, e.name, e.id, e.ttl)
+	cancel()
+	if err != nil {
+		// Cannot prove we still hold the lease; step down rather than risk two leaders.
+		if ctx.Err() == nil {
+			slog.ErrorContext(ctx, "leader: lease error", "lease", e.name, "instance_id", e.id, "err", err)
+		}
+		ok = false
+	}
+	was := e.leading.Swap(ok)
+	switch {
+	case ok && !was:
+		slog.InfoContext(ctx, "leader: acquired lease", "lease", e.name, "instance_id", e.id)
+		select {
+		case e.elected <- struct{}{}:
+		default:
+		}
+	case !ok && was:
+		slog.WarnContext(ctx, "leader: lost lease", "lease", e.name, "instance_id", e.id)
+	}
+}
+
+func (e *Elector) release() {
+	if !e.leading.Swap(false) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := e.db.ReleaseLease(ctx, e.name, e.id); err != nil {
+		slog.Error("leader: release error", "lease", e.name, "instance_id", e.id, "err", err)
+		return
+	}
+	slog.Info("leader: released lease", "lease", e.name, "instance_id", e.id)
+}
+
+// instanceID combines the hostname (the pod name under Kubernetes) with a
+// random suffix so restarts of the same pod get a fresh identity.
+func instanceID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "scheduler"
+	}
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%s-%d", host, time.Now().UnixNano())
+	}
+	return host + "-" + hex.EncodeToString(b)
+}
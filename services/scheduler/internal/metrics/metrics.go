@@ -0,0 +1,84 @@
+// Package metrics defines the scheduler's Prometheus series. They register
+// with the default registry, served on /metrics.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Scheduler loop and outbox relay.
+var (
+	TickDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "scheduler_tick_duration_seconds",
+		Help:    "Duration of scheduler ticks.",
+		Buckets: []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120},
+	})
+	ObjectivesEvaluated = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "scheduler_objectives_evaluated_total",
+		Help: "Active objectives evaluated by ticks.",
+	})
+	ObjectivesExecuted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "scheduler_objectives_executed_total",
+		Help: "Objective runs enqueued, by trigger (scheduled, backfill or manual).",
+	}, []string{"trigger"})
+	ManifestsPublished = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "scheduler_manifests_published_total",
+		Help: "Manifest events published by the outbox relay.",
+	})
+	QuestionsPublished = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "scheduler_questions_published_total",
+		Help: "Question events published by the outbox relay, including watchdog re-emits.",
+	})
+)
+
+// Spend and budgets.
+var (
+	SpendUSD = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "scheduler_spend_usd_total",
+		Help: "Estimated cost of answers by model, from the pricing table.",
+	}, []string{"model"})
+	SpendTokens = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "scheduler_spend_tokens_total",
+		Help: "Tokens of answers by model and kind (input or output). Cache hits count none.",
+	}, []string{"model", "kind"})
+	RunsOverBudget = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "scheduler_runs_over_budget_total",
+		Help: "Runs not executed because a budget was exhausted, by scope (objective or partner), period and action (defer, skip or refuse).",
+	}, []string{"scope", "period", "action"})
+)
+
+// Kafka consumer.
+var (
+	ConsumeLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "scheduler_kafka_consume_latency_seconds",
+		Help:    "Time from a message's produce timestamp until it was fetched, by topic.",
+		Buckets: []float64{.01, .05, .1, .5, 1, 5, 10, 30, 60, 300, 900},
+	}, []string{"topic"})
+	DispatchDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "scheduler_kafka_dispatch_duration_seconds",
+		Help:    "Handler duration by topic.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"topic"})
+	ConsumerErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "scheduler_kafka_consumer_errors_total",
+		Help: "Consumer errors by topic and stage (fetch, dispatch or commit).",
+	}, []string{"topic", "stage"})
+	MessagesQuarantined = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "scheduler_kafka_messages_quarantined_total",
+		Help: "Consumed messages that failed schema validation, by source topic.",
+	}, []string{"topic"})
+)
+
+// Kafka producer.
+var (
+	PublishDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "scheduler_kafka_publish_duration_seconds",
+		Help:    "Publish latency by topic.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"topic"})
+	PublishFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "scheduler_kafka_publish_failures_total",
+		Help: "Failed publishes by topic, including payloads refused by schema validation.",
+	}, []string{"topic"})
+)
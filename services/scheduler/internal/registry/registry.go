@@ -0,0 +1,105 @@
+// Package registry maps Kafka topics to the decoder and handlers of their
+// events. Components subscribe their handlers at startup; the consumer reads
+// every registered topic and dispatches through the registry.
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"llm-your-business/services/scheduler/internal/decode"
+	"llm-your-business/services/scheduler/internal/logging"
+)
+
+// Registry holds the subscriptions per topic. Subscribe before the consumer
+// starts; a Registry is not safe for subscribing concurrently with Dispatch.
+type Registry struct {
+	routes map[string]*route
+	topics []string // registration order
+}
+
+type route struct {
+	decoder  any // *decode.Decoder[T], to reject a second decoder for the topic
+	decode   func([]byte) (any, error)
+	logAttrs func(any) []any
+	handlers []handler
+}
+
+type handler struct {
+	name string
+	fn   func(context.Context, any) error
+}
+
+func New() *Registry {
+	return &Registry{routes: make(map[string]*route)}
+}
+
+// Subscribe registers fn, under name, for the events d decodes from d's topic.
+// A topic may have several handlers; they all see each message, in
+// subscription order. All subscriptions to a topic must use the same decoder.
+func Subscribe[T any](r *Registry, d *decode.Decoder[T], name string, fn func(context.Context, T) error) {
+	rt, ok := r.routes[d.Topic()]
+	if !ok {
+		rt = &route{
+			decoder: d,
+			decode: func(payload []byte) (any, error) {
+				return d.Decode(payload)
+			},
+			logAttrs: func(evt any) []any { return d.LogAttrs(evt.(T)) },
+		}
+		r.routes[d.Topic()] = rt
+		r.topics = append(r.topics, d.Topic())
+	} else if rt.decoder != any(d) {
+		panic(fmt.Sprintf("registry: %s is already registered with another decoder", d.Topic()))
+	}
+	for _, h := range rt.handlers {
+		if h.name == name {
+			panic(fmt.Sprintf("registry: handler %q already subscribed to %s", name, d.Topic()))
+		}
+	}
+	rt.handlers = append(rt.handlers, handler{name: name, fn: func(ctx context.Context, evt any) error {
+		return fn(ctx, evt.(T))
+	}})
+}
+
+// Topics returns the topics that have handlers, in registration order.
+func (r *Registry) Topics() []string {
+	return append([]string(nil), r.topics...)
+}
+
+// Has reports whether topic has handlers.
+func (r *Registry) Has(topic string) bool {
+	_, ok := r.routes[topic]
+	return ok
+}
+
+// Dispatch decodes payload once and passes the event to every handler of
+// topic, with the event's log attributes on ctx. A handler's failure does not
+// stop the others; their errors are joined. The whole message is retried on
+// failure, so handlers must tolerate seeing an event again.
+func (r *Registry) Dispatch(ctx context.Context, topic string, payload []byte) error {
+	rt, ok := r.routes[topic]
+	if !ok {
+		return fmt.Errorf("no handlers for topic %s", topic)
+	}
+	evt, err := rt.decode(payload)
+	if err != nil {
+		return &DecodeError{Err: err}
+	}
+	ctx = logging.With(ctx, rt.logAttrs(evt)...)
+	var errs []error
+	for _, h := range rt.handlers {
+		if err := h.fn(ctx, evt); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", h.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DecodeError reports a payload that could not be decoded. Retrying does not
+// help.
+type DecodeError struct{ Err error }
+
+func (e *DecodeError) Error() string { return e.Err.Error() }
+func (e *DecodeError) Unwrap() error { return e.Err }
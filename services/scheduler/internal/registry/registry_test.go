@@ -0,0 +1,155 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"llm-your-business/services/scheduler/internal/decode"
+)
+
+// answerV1 and answerV2 stand in for two generated versions of one schema:
+// v2 renamed text to content and added a tokens count.
+type answerV1 struct {
+	Meta struct {
+		SchemaVersion int    `json:"schema_version"`
+		QuestionId    string `json:"question_id"`
+	} `json:"meta"`
+	Data struct {
+		Text string `json:"text"`
+	} `json:"data"`
+}
+
+type answerV2 struct {
+	Meta answerMeta `json:"meta"`
+	Data answerData `json:"data"`
+}
+
+type answerMeta struct {
+	SchemaVersion int    `json:"schema_version"`
+	QuestionId    string `json:"question_id"`
+}
+
+type answerData struct {
+	Content string `json:"content"`
+	Tokens  int    `json:"tokens"`
+}
+
+func answerV1ToV2(old answerV1) (answerV2, error) {
+	if old.Data.Text == "" {
+		return answerV2{}, errors.New("empty text")
+	}
+	return answerV2{
+		Meta: answerMeta{SchemaVersion: 2, QuestionId: old.Meta.QuestionId},
+		Data: answerData{Content: old.Data.Text},
+	}, nil
+}
+
+func TestDispatchUpcasts(t *testing.T) {
+	d := decode.Upcast(decode.New[answerV2]("answers", 2), 1, answerV1ToV2)
+	reg := New()
+	var got []answerV2
+	Subscribe(reg, d, "record", func(_ context.Context, evt answerV2) error {
+		got = append(got, evt)
+		return nil
+	})
+
+	tests := []struct {
+		name    string
+		payload string
+		want    answerV2
+	}{
+		{
+			name:    "v1 is upcast",
+			payload: `{"meta":{"schema_version":1,"question_id":"q1"},"data":{"text":"1. Acme"}}`,
+			want:    answerV2{Meta: answerMeta{SchemaVersion: 2, QuestionId: "q1"}, Data: answerData{Content: "1. Acme"}},
+		},
+		{
+			name:    "missing version is v1",
+			payload: `{"meta":{"question_id":"q2"},"data":{"text":"1. Bolt"}}`,
+			want:    answerV2{Meta: answerMeta{SchemaVersion: 2, QuestionId: "q2"}, Data: answerData{Content: "1. Bolt"}},
+		},
+		{
+			name:    "v2 is decoded as is",
+			payload: `{"meta":{"schema_version":2,"question_id":"q3"},"data":{"content":"1. Core","tokens":7}}`,
+			want:    answerV2{Meta: answerMeta{SchemaVersion: 2, QuestionId: "q3"}, Data: answerData{Content: "1. Core", Tokens: 7}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = nil
+			if err := reg.Dispatch(context.Background(), "answers", []byte(tt.payload)); err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 1 || !reflect.DeepEqual(got[0], tt.want) {
+				t.Fatalf("handler saw %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if !reflect.DeepEqual(d.Versions(), []int{1, 2}) {
+		t.Fatalf("Versions() = %v, want [1 2]", d.Versions())
+	}
+}
+
+func TestDispatchDecodeErrors(t *testing.T) {
+	d := decode.Upcast(decode.New[answerV2]("answers", 2), 1, answerV1ToV2)
+	reg := New()
+	called := false
+	Subscribe(reg, d, "record", func(context.Context, answerV2) error {
+		called = true
+		return nil
+	})
+
+	tests := []struct {
+		name        string
+		payload     string
+		wantVersion bool
+	}{
+		{name: "unknown version", payload: `{"meta":{"schema_version":3}}`, wantVersion: true},
+		{name: "upcaster fails", payload: `{"meta":{"schema_version":1},"data":{"text":""}}`},
+		{name: "not json", payload: `{`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := reg.Dispatch(context.Background(), "answers", []byte(tt.payload))
+			var decodeErr *DecodeError
+			if !errors.As(err, &decodeErr) {
+				t.Fatalf("err = %v, want a *DecodeError", err)
+			}
+			var versionErr *decode.VersionError
+			if got := errors.As(err, &versionErr); got != tt.wantVersion {
+				t.Fatalf("err = %v, VersionError %v, want %v", err, got, tt.wantVersion)
+			}
+			if called {
+				t.Fatal("handler ran for an undecodable payload")
+			}
+		})
+	}
+}
+
+func TestDispatchRunsEveryHandler(t *testing.T) {
+	d := decode.New[answerV2]("answers", 2)
+	reg := New()
+	var ran []string
+	Subscribe(reg, d, "first", func(context.Context, answerV2) error {
+		ran = append(ran, "first")
+		return errors.New("boom")
+	})
+	Subscribe(reg, d, "second", func(context.Context, answerV2) error {
+		ran = append(ran, "second")
+		return nil
+	})
+
+	err := reg.Dispatch(context.Background(), "answers", []byte(`{"meta":{"schema_version":2}}`))
+	if err == nil || err.Error() != "first: boom" {
+		t.Fatalf("err = %v, want first: boom", err)
+	}
+	if !reflect.DeepEqual(ran, []string{"first", "second"}) {
+		t.Fatalf("handlers ran %v", ran)
+	}
+	if err := reg.Dispatch(context.Background(), "questions", nil); err == nil {
+		t.Fatal("dispatch to a topic without handlers succeeded")
+	}
+}
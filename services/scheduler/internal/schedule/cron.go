@@ -0,0 +1,264 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cron is a parsed 5-field cron expression: minute hour day-of-month month
+// day-of-week. Beyond the usual lists, ranges, steps and names it supports
+// "L" (last day of the month) in day-of-month and "DOW#n" (n-th weekday of
+// the month, e.g. "MON#1") in day-of-week. As in Vixie cron, when both
+// day fields are restricted a day matching either one is due.
+type cron struct {
+	minute, hour, dom, month, dow uint64 // bit i set => value i allowed
+	domAny, dowAny                bool
+	lastDom                       bool
+	nth                           []nthWeekday
+	loc                           *time.Location
+}
+
+type nthWeekday struct {
+	weekday time.Weekday
+	n       int // 1..5
+}
+
+var (
+	monthNames   = map[string]int{"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6, "JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12}
+	weekdayNames = map[string]int{"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6}
+	descriptors  = map[string]string{
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+		"@monthly":  "0 0 1 * *",
+		"@weekly":   "0 0 * * 0",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@hourly":   "0 * * * *",
+	}
+)
+
+// parseCron parses expr, evaluated in loc.
+func parseCron(expr string, loc *time.Location) (*cron, error) {
+	expr = strings.TrimSpace(expr)
+	if d, ok := descriptors[strings.ToLower(expr)]; ok {
+		expr = d
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron %q: want 5 fields, got %d", expr, len(fields))
+	}
+	c := &cron{loc: loc}
+	var err error
+	if c.minute, _, err = parseField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("cron %q minute: %w", expr, err)
+	}
+	if c.hour, _, err = parseField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("cron %q hour: %w", expr, err)
+	}
+	if err = c.parseDom(fields[2]); err != nil {
+		return nil, fmt.Errorf("cron %q day-of-month: %w", expr, err)
+	}
+	if c.month, _, err = parseField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, fmt.Errorf("cron %q month: %w", expr, err)
+	}
+	if err = c.parseDow(fields[4]); err != nil {
+		return nil, fmt.Errorf("cron %q day-of-week: %w", expr, err)
+	}
+	return c, nil
+}
+
+func (c *cron) parseDom(field string) error {
+	var parts []string
+	for _, p := range strings.Split(field, ",") {
+		if strings.EqualFold(p, "L") {
+			c.lastDom = true
+			continue
+		}
+		parts = append(parts, p)
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	bits, any, err := parseField(strings.Join(parts, ","), 1, 31, nil)
+	if err != nil {
+		return err
+	}
+	c.dom, c.domAny = bits, any && !c.lastDom
+	return nil
+}
+
+func (c *cron) parseDow(field string) error {
+	var parts []string
+	for _, p := range strings.Split(field, ",") {
+		if i := strings.Index(p, "#"); i > 0 {
+			wd, err := parseValue(p[:i], 0, 7, weekdayNames)
+			if err != nil {
+				return err
+			}
+			n, err := strconv.Atoi(p[i+1:])
+			if err != nil || n < 1 || n > 5 {
+				return fmt.Errorf("%q: occurrence must be 1-5", p)
+			}
+			c.nth = append(c.nth, nthWeekday{weekday: time.Weekday(wd % 7), n: n})
+			continue
+		}
+		parts = append(parts, p)
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	bits, any, err := parseField(strings.Join(parts, ","), 0, 7, weekdayNames)
+	if err != nil {
+		return err
+	}
+	if bits&(1<<7) != 0 { // 7 is Sunday too
+		bits |= 1
+	}
+	c.dow, c.dowAny = bits, any && len(c.nth) == 0
+	return nil
+}
+
+// parseField parses a comma-separated list of "*", "?", "a", "a-b" with an
+// optional "/step". any reports whether the field was an unrestricted "*".
+func parseField(field string, min, max int, names map[string]int) (bits uint64, any bool, err error) {
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			rangePart = part[:i]
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step < 1 {
+				return 0, false, fmt.Errorf("%q: bad step", part)
+			}
+		}
+		lo, hi := min, max
+		switch {
+		case rangePart == "*" || rangePart == "?":
+			if step == 1 && len(field) == len(part) {
+				any = true
+			}
+		case strings.Contains(rangePart, "-"):
+			i := strings.Index(rangePart, "-")
+			if lo, err = parseValue(rangePart[:i], min, max, names); err != nil {
+				return 0, false, err
+			}
+			if hi, err = parseValue(rangePart[i+1:], min, max, names); err != nil {
+				return 0, false, err
+			}
+			if lo > hi {
+				return 0, false, fmt.Errorf("%q: range start after end", part)
+			}
+		default:
+			if lo, err = parseValue(rangePart, min, max, names); err != nil {
+				return 0, false, err
+			}
+			if strings.Contains(part, "/") {
+				hi = max // "a/step" means from a to the end
+			} else {
+				hi = lo
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, any, nil
+}
+
+func parseValue(s string, min, max int, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToUpper(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("%q: not a number", s)
+	}
+	if v < min || v > max {
+		return 0, fmt.Errorf("%d out of range %d-%d", v, min, max)
+	}
+	return v, nil
+}
+
+// next returns the first matching minute strictly after t, or the zero time
+// if none exists within five years (e.g. "0 0 30 2 *").
+//
+// It walks wall-clock time, which has no DST gaps or repeats, and converts
+// each match to loc. A slot in the hour skipped when clocks go forward runs
+// once the clocks have jumped (02:30 becomes 03:30), and a slot in the hour
+// repeated when they go back runs only the first time round.
+func (c *cron) next(t time.Time) time.Time {
+	local := t.In(c.loc)
+	w := time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), local.Minute(), 0, 0, time.UTC).Add(time.Minute)
+	limit := w.AddDate(5, 0, 0)
+	for w.Before(limit) {
+		if c.month&(1<<uint(w.Month())) == 0 {
+			w = time.Date(w.Year(), w.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !c.dayMatches(w) {
+			w = time.Date(w.Year(), w.Month(), w.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if c.hour&(1<<uint(w.Hour())) == 0 {
+			w = w.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		if c.minute&(1<<uint(w.Minute())) == 0 {
+			w = w.Add(time.Minute)
+			continue
+		}
+		// In the repeated hour, the wall-clock time may already have
+		// passed the first time round.
+		if at := c.at(w); at.After(t) {
+			return at
+		}
+		w = w.Add(time.Minute)
+	}
+	return time.Time{}
+}
+
+// at converts the wall-clock time w, held in UTC, to loc. time.Date leaves
+// the result unspecified for times in a DST gap or overlap, so those are
+// resolved with the offset in effect before the transition: a time in the
+// gap moves forward by the jump, and a time in the overlap is its first
+// occurrence.
+func (c *cron) at(w time.Time) time.Time {
+	_, before := time.Date(w.Year(), w.Month(), w.Day()-1, w.Hour(), w.Minute(), 0, 0, c.loc).Zone()
+	early := w.Add(-time.Duration(before) * time.Second).In(c.loc)
+	if sameWall(early, w) {
+		return early
+	}
+	if t := time.Date(w.Year(), w.Month(), w.Day(), w.Hour(), w.Minute(), 0, 0, c.loc); sameWall(t, w) {
+		return t // the offset changed earlier in the day
+	}
+	return early // w is in the gap
+}
+
+// sameWall reports whether t reads w on the clock.
+func sameWall(t, w time.Time) bool {
+	return t.Year() == w.Year() && t.YearDay() == w.YearDay() && t.Hour() == w.Hour() && t.Minute() == w.Minute()
+}
+
+func (c *cron) dayMatches(t time.Time) bool {
+	domMatch := c.dom&(1<<uint(t.Day())) != 0 || (c.lastDom && t.Day() == daysIn(t))
+	dowMatch := c.dow&(1<<uint(t.Weekday())) != 0
+	for _, n := range c.nth {
+		if t.Weekday() == n.weekday && (t.Day()-1)/7+1 == n.n {
+			dowMatch = true
+		}
+	}
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dowMatch
+	case c.dowAny:
+		return domMatch
+	default:
+		return domMatch || dowMatch
+	}
+}
+
+func daysIn(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+}
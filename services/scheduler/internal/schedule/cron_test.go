@@ -0,0 +1,237 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata" // the DST cases must not depend on the host's zoneinfo
+
+	model "llm-your-business/services/go/models"
+)
+
+func objective(schedule model.RunSchedule, expr, zone string, start time.Time) model.ObjectiveV1Json {
+	return model.ObjectiveV1Json{RunSchedule: schedule, ScheduleCron: expr, TimeZone: zone, StartDate: start}
+}
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestParseCronErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string // substring of the error
+	}{
+		{"* * * *", "want 5 fields"},
+		{"* * * * * *", "want 5 fields"},
+		{"60 * * * *", "minute"},
+		{"* 24 * * *", "hour"},
+		{"* * 0 * *", "day-of-month"},
+		{"* * 32 * *", "day-of-month"},
+		{"* * * 13 *", "month"},
+		{"* * * JUNE *", "month"},
+		{"* * * * 8", "day-of-week"},
+		{"*/0 * * * *", "bad step"},
+		{"*/x * * * *", "bad step"},
+		{"30-10 * * * *", "range start after end"},
+		{"* * * * MON#0", "occurrence must be 1-5"},
+		{"* * * * MON#6", "occurrence must be 1-5"},
+		{"* * * * XYZ#1", "not a number"},
+		{"a * * * *", "not a number"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := parseCron(tt.expr, time.UTC)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parseCron(%q) error = %v, want it to mention %q", tt.expr, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	berlin := mustLoad(t, "Europe/Berlin")
+	newYork := mustLoad(t, "America/New_York")
+	tests := []struct {
+		name string
+		expr string
+		loc  *time.Location
+		from time.Time
+		want []time.Time // the next slots after from, in order
+	}{
+		{
+			name: "descriptor",
+			expr: "@daily",
+			loc:  time.UTC,
+			from: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			want: []time.Time{time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "weekdays with names",
+			expr: "0 9 * * MON-FRI",
+			loc:  time.UTC,
+			from: time.Date(2026, 1, 9, 9, 0, 0, 0, time.UTC), // a Friday, at the slot
+			want: []time.Time{time.Date(2026, 1, 12, 9, 0, 0, 0, time.UTC), time.Date(2026, 1, 13, 9, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "sunday as 7",
+			expr: "0 0 * * 7",
+			loc:  time.UTC,
+			from: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			want: []time.Time{time.Date(2026, 1, 4, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "steps from a start value",
+			expr: "10/20 8 * * *",
+			loc:  time.UTC,
+			from: time.Date(2026, 1, 1, 8, 10, 0, 0, time.UTC),
+			want: []time.Time{time.Date(2026, 1, 1, 8, 30, 0, 0, time.UTC), time.Date(2026, 1, 1, 8, 50, 0, 0, time.UTC), time.Date(2026, 1, 2, 8, 10, 0, 0, time.UTC)},
+		},
+		{
+			name: "day of month or day of week",
+			expr: "0 0 13 * FRI",
+			loc:  time.UTC,
+			from: time.Date(2026, 2, 8, 0, 0, 0, 0, time.UTC),
+			want: []time.Time{
+				time.Date(2026, 2, 13, 0, 0, 0, 0, time.UTC), // Friday the 13th
+				time.Date(2026, 2, 20, 0, 0, 0, 0, time.UTC), // Friday
+				time.Date(2026, 2, 27, 0, 0, 0, 0, time.UTC),
+				time.Date(2026, 3, 6, 0, 0, 0, 0, time.UTC),
+				time.Date(2026, 3, 13, 0, 0, 0, 0, time.UTC), // the 13th, and a Friday again
+			},
+		},
+		{
+			name: "last day of february in a leap year",
+			expr: "0 6 L 2 *",
+			loc:  time.UTC,
+			from: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+			want: []time.Time{time.Date(2027, 2, 28, 6, 0, 0, 0, time.UTC), time.Date(2028, 2, 29, 6, 0, 0, 0, time.UTC), time.Date(2029, 2, 28, 6, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "last day together with a listed day",
+			expr: "0 0 1,L * *",
+			loc:  time.UTC,
+			from: time.Date(2028, 2, 2, 0, 0, 0, 0, time.UTC),
+			want: []time.Time{time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC), time.Date(2028, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2028, 3, 31, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "first monday",
+			expr: "0 9 * * MON#1",
+			loc:  time.UTC,
+			from: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			want: []time.Time{time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC), time.Date(2026, 2, 2, 9, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "fifth friday skips months without one",
+			expr: "0 9 * * FRI#5",
+			loc:  time.UTC,
+			from: time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC),
+			want: []time.Time{time.Date(2026, 5, 29, 9, 0, 0, 0, time.UTC), time.Date(2026, 7, 31, 9, 0, 0, 0, time.UTC), time.Date(2026, 10, 30, 9, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "never",
+			expr: "0 0 30 2 *",
+			loc:  time.UTC,
+			from: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			want: []time.Time{{}},
+		},
+		{
+			name: "local time across spring forward",
+			expr: "0 9 * * *",
+			loc:  berlin,
+			from: time.Date(2026, 3, 28, 9, 0, 0, 0, berlin),
+			want: []time.Time{time.Date(2026, 3, 29, 9, 0, 0, 0, berlin), time.Date(2026, 3, 30, 9, 0, 0, 0, berlin)},
+		},
+		{
+			name: "slot in the skipped hour runs after the jump",
+			expr: "30 2 * * *",
+			loc:  berlin,
+			from: time.Date(2026, 3, 28, 12, 0, 0, 0, berlin),
+			want: []time.Time{
+				time.Date(2026, 3, 29, 1, 30, 0, 0, time.UTC), // 03:30 CEST
+				time.Date(2026, 3, 30, 2, 30, 0, 0, berlin),
+			},
+		},
+		{
+			name: "slot in the skipped hour, zone behind UTC",
+			expr: "30 2 * * *",
+			loc:  newYork,
+			from: time.Date(2026, 3, 7, 12, 0, 0, 0, newYork),
+			want: []time.Time{
+				time.Date(2026, 3, 8, 7, 30, 0, 0, time.UTC), // 03:30 EDT
+				time.Date(2026, 3, 9, 2, 30, 0, 0, newYork),
+			},
+		},
+		{
+			name: "slot in the repeated hour runs once",
+			expr: "30 2 * * *",
+			loc:  berlin,
+			from: time.Date(2026, 10, 24, 12, 0, 0, 0, berlin),
+			want: []time.Time{
+				time.Date(2026, 10, 25, 0, 30, 0, 0, time.UTC), // 02:30 CEST
+				time.Date(2026, 10, 26, 2, 30, 0, 0, berlin),
+			},
+		},
+		{
+			name: "from inside the repeated hour",
+			expr: "30 1 * * *",
+			loc:  newYork,
+			from: time.Date(2026, 11, 1, 6, 10, 0, 0, time.UTC), // 01:10 EST, the second time round
+			want: []time.Time{time.Date(2026, 11, 2, 1, 30, 0, 0, newYork)},
+		},
+		{
+			name: "every half hour across fall back",
+			expr: "0,30 * * * *",
+			loc:  berlin,
+			from: time.Date(2026, 10, 25, 0, 0, 0, 0, time.UTC), // 02:00 CEST
+			want: []time.Time{
+				time.Date(2026, 10, 25, 0, 30, 0, 0, time.UTC), // 02:30 CEST
+				time.Date(2026, 10, 25, 2, 0, 0, 0, time.UTC),  // 03:00 CET
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := parseCron(tt.expr, tt.loc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			from := tt.from
+			for i, want := range tt.want {
+				got := c.next(from)
+				if !got.Equal(want) {
+					t.Fatalf("slot %d after %v = %v, want %v", i, from, got, want.In(tt.loc))
+				}
+				from = got
+			}
+		})
+	}
+}
+
+func TestScheduleBetween(t *testing.T) {
+	s, err := For(objective(model.RunScheduleCron, "0 */6 * * *", "Europe/Berlin", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc := s.Location()
+	// Before the start date, slots begin at its local midnight.
+	got := s.Between(time.Date(2026, 2, 1, 0, 0, 0, 0, loc), time.Date(2026, 3, 1, 12, 0, 0, 0, loc), 0)
+	want := []time.Time{
+		time.Date(2026, 3, 1, 0, 0, 0, 0, loc),
+		time.Date(2026, 3, 1, 6, 0, 0, 0, loc),
+		time.Date(2026, 3, 1, 12, 0, 0, 0, loc),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Between = %v, want %v", got, want)
+	}
+	for i := range want {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("slot %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
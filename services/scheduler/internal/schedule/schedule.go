@@ -0,0 +1,158 @@
+// Package schedule works out when an objective is due to run, from either
+// its cron expression or its legacy daily/weekly/monthly cadence, in the
+// objective's own time zone.
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	model "llm-your-business/services/go/models"
+)
+
+// spec yields the schedule's slots; next returns the first slot strictly
+// after t, or the zero time when there is none.
+type spec interface {
+	next(t time.Time) time.Time
+}
+
+// Schedule is an objective's run schedule bound to its time zone.
+type Schedule struct {
+	spec  spec
+	loc   *time.Location
+	start time.Time // local midnight of the objective's start date; zero means unset
+}
+
+// For builds the schedule of obj. An empty TimeZone means UTC.
+func For(obj model.ObjectiveV1Json) (*Schedule, error) {
+	loc := time.UTC
+	if obj.TimeZone != "" {
+		l, err := time.LoadLocation(obj.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("time zone %q: %w", obj.TimeZone, err)
+		}
+		loc = l
+	}
+
+	s := &Schedule{loc: loc}
+	if !obj.StartDate.IsZero() {
+		// start_date is a calendar date; read it in the objective's zone.
+		d := obj.StartDate.UTC()
+		s.start = time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, loc)
+	}
+
+	switch obj.RunSchedule {
+	case model.RunScheduleCron:
+		if obj.ScheduleCron == "" {
+			return nil, errors.New("run_schedule is cron but schedule_cron is empty")
+		}
+		c, err := parseCron(obj.ScheduleCron, loc)
+		if err != nil {
+			return nil, err
+		}
+		s.spec = c
+	default:
+		if s.start.IsZero() {
+			return nil, fmt.Errorf("run_schedule %q needs a start_date", obj.RunSchedule)
+		}
+		s.spec = calendar{kind: obj.RunSchedule, anchor: s.start}
+	}
+	return s, nil
+}
+
+// Location is the zone the schedule is evaluated in.
+func (s *Schedule) Location() *time.Location { return s.loc }
+
+// Next returns the first slot strictly after t on or after the start date,
+// or the zero time when there is none.
+func (s *Schedule) Next(t time.Time) time.Time {
+	if t.Before(s.start) {
+		t = s.start.Add(-time.Nanosecond)
+	}
+	return s.spec.next(t)
+}
+
+// Between returns the slots in (after, until], oldest first. With max > 0
+// it stops after the first max slots.
+func (s *Schedule) Between(after, until time.Time, max int) []time.Time {
+	var out []time.Time
+	for t := s.Next(after); !t.IsZero() && !t.After(until); t = s.Next(t) {
+		if max > 0 && len(out) == max {
+			break
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+// Latest returns the last slot in (after, until], or false when there is
+// none. It searches back from until in doubling windows, so the work depends
+// on how far back that slot lies rather than on how long the range is.
+func (s *Schedule) Latest(after, until time.Time) (time.Time, bool) {
+	for window := time.Hour; ; window *= 2 {
+		from := until.Add(-window)
+		if !from.After(after) {
+			from = after
+		}
+		var latest time.Time
+		for t := s.Next(from); !t.IsZero() && !t.After(until); t = s.Next(t) {
+			latest = t
+		}
+		if !latest.IsZero() {
+			return latest, true
+		}
+		if from.Equal(after) {
+			return time.Time{}, false
+		}
+	}
+}
+
+// DayStart returns local midnight of t's calendar day in the schedule's zone.
+func (s *Schedule) DayStart(t time.Time) time.Time {
+	local := t.In(s.loc)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, s.loc)
+}
+
+// calendar is the legacy cadence: one run at local midnight every day, every
+// seventh day from the anchor, or monthly on the anchor's day of month
+// (the last day of shorter months).
+type calendar struct {
+	kind   model.RunSchedule
+	anchor time.Time
+}
+
+func (c calendar) next(t time.Time) time.Time {
+	loc := c.anchor.Location()
+	local := t.In(loc)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	if !day.After(t) {
+		day = day.AddDate(0, 0, 1)
+	}
+	// Monthly is the sparsest cadence, so a match is always within 31 days.
+	for i := 0; i < 32; i++ {
+		if c.matches(day) {
+			return day
+		}
+		day = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, loc)
+	}
+	return time.Time{}
+}
+
+func (c calendar) matches(day time.Time) bool {
+	switch c.kind {
+	case model.RunScheduleWeekly:
+		// Count calendar days, not hours, so DST shifts do not skew the week.
+		a := time.Date(c.anchor.Year(), c.anchor.Month(), c.anchor.Day(), 0, 0, 0, 0, time.UTC)
+		d := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+		return int(d.Sub(a).Hours()/24)%7 == 0
+	case model.RunScheduleMonthly:
+		want := c.anchor.Day()
+		if last := daysIn(day); want > last {
+			return day.Day() == last
+		}
+		return day.Day() == want
+	default:
+		return true
+	}
+}
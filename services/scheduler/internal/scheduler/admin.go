@@ -0,0 +1,36 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	model "llm-your-business/services/go/models"
+	"llm-your-business/services/scheduler/internal/schedule"
+)
+
+// TriggerObjective runs an objective now, outside its schedule and whether
+// or not it is active or paused. The run is recorded as manual and does not
+// count against any schedule slot. Budgets still apply: an exhausted one is
+// returned as a *budget.Exceeded. Returns the manifest_id, or "" when the
+// objective has no questions.
+func (s *Service) TriggerObjective(ctx context.Context, id string) (string, error) {
+	obj, err := s.DB.FindObjective(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	return s.executeObjective(ctx, id, obj, model.ObjectiveV1JsonRunsElem{Timestamp: time.Now().UTC(), Manual: true})
+}
+
+// NextRun returns the objective's next schedule slot after now, or the zero
+// time when it is paused or its schedule has no further slots.
+// Missed slots the next tick would catch up are not reported.
+func NextRun(obj model.ObjectiveV1Json, now time.Time) (time.Time, error) {
+	sched, err := schedule.For(obj)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if obj.Paused {
+		return time.Time{}, nil
+	}
+	return sched.Next(now), nil
+}
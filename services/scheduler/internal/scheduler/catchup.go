@@ -0,0 +1,88 @@
+package scheduler
+
+import (
+	"time"
+
+	model "llm-your-business/services/go/models"
+	"llm-your-business/services/scheduler/internal/config"
+	"llm-your-business/services/scheduler/internal/schedule"
+)
+
+// planRuns returns the runs a tick at now should execute for an objective,
+// oldest first. Slots after the last recorded run are candidates. The latest
+// one is on time when it falls on today's local calendar day; every other
+// candidate was missed, and is run as a backfill or dropped according to
+// policy. CatchUpAll runs at most max missed slots per tick, so a long outage
+// drains over several ticks. An objective that has never run has nothing to
+// catch up, and slots before it was last resumed were paused, not missed.
+func planRuns(sched *schedule.Schedule, obj model.ObjectiveV1Json, now time.Time, policy string, max int) []model.ObjectiveV1JsonRunsElem {
+	today := sched.DayStart(now)
+	after, ok := lastCovered(obj.Runs)
+	if obj.ResumedAt != nil && obj.ResumedAt.After(after) {
+		after, ok = *obj.ResumedAt, true
+	}
+	if !ok || (policy == config.CatchUpSkip && after.Before(today)) {
+		after = today.Add(-time.Nanosecond)
+	}
+
+	// Only today's latest slot and the missed slots the policy runs are
+	// looked up, so a long outage on a frequent schedule costs no more than
+	// a short one.
+	var current time.Time
+	from := after
+	if from.Before(today) {
+		from = today.Add(-time.Nanosecond)
+	}
+	if t, ok := sched.Latest(from, now); ok {
+		current = t
+	}
+	beforeToday := today.Add(-time.Nanosecond)
+
+	var out []model.ObjectiveV1JsonRunsElem
+	switch {
+	case !after.Before(beforeToday):
+		// Nothing before today is left uncovered.
+	case policy == config.CatchUpLatest:
+		if current.IsZero() {
+			if t, ok := sched.Latest(after, beforeToday); ok {
+				out = append(out, model.ObjectiveV1JsonRunsElem{ScheduledFor: t, Backfill: true})
+			}
+		}
+	case policy == config.CatchUpAll:
+		missed := sched.Between(after, beforeToday, max+1)
+		if len(missed) > max {
+			missed = missed[:max]
+			current = time.Time{} // the backlog goes first
+		}
+		for _, t := range missed {
+			out = append(out, model.ObjectiveV1JsonRunsElem{ScheduledFor: t, Backfill: true})
+		}
+	}
+	if !current.IsZero() {
+		out = append(out, model.ObjectiveV1JsonRunsElem{ScheduledFor: current})
+	}
+	for i := range out {
+		out[i].Timestamp = now
+	}
+	return out
+}
+
+// lastCovered returns the latest slot a recorded run covers. Runs recorded
+// before slots were tracked cover everything up to their timestamp; manual
+// runs cover nothing.
+func lastCovered(runs []model.ObjectiveV1JsonRunsElem) (time.Time, bool) {
+	var latest time.Time
+	for _, r := range runs {
+		if r.Manual {
+			continue
+		}
+		t := r.ScheduledFor
+		if t.IsZero() {
+			t = r.Timestamp
+		}
+		if t.After(latest) {
+			latest = t
+		}
+	}
+	return latest, !latest.IsZero()
+}
@@ -0,0 +1,111 @@
+package scheduler
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	model "llm-your-business/services/go/models"
+	"llm-your-business/services/scheduler/internal/config"
+	"llm-your-business/services/scheduler/internal/schedule"
+)
+
+func TestPlanRuns(t *testing.T) {
+	day := func(d, h int) time.Time { return time.Date(2026, 3, d, h, 0, 0, 0, time.UTC) }
+	ran := func(slots ...time.Time) []model.ObjectiveV1JsonRunsElem {
+		var runs []model.ObjectiveV1JsonRunsElem
+		for _, s := range slots {
+			runs = append(runs, model.ObjectiveV1JsonRunsElem{ScheduledFor: s, Timestamp: s})
+		}
+		return runs
+	}
+	type run struct {
+		slot     time.Time
+		backfill bool
+	}
+	tests := []struct {
+		name    string
+		cron    string
+		runs    []model.ObjectiveV1JsonRunsElem
+		resumed *time.Time
+		now     time.Time
+		policy  string
+		max     int
+		want    []run
+	}{
+		{name: "never run, before today's slot", now: day(10, 8), policy: config.CatchUpAll},
+		{name: "never run, after today's slot", now: day(10, 12), policy: config.CatchUpAll, want: []run{{day(10, 9), false}}},
+		{name: "today's slot already run", runs: ran(day(10, 9)), now: day(10, 12), policy: config.CatchUpAll},
+
+		{name: "skip drops missed slots", runs: ran(day(5, 9)), now: day(10, 12), policy: config.CatchUpSkip, want: []run{{day(10, 9), false}}},
+		{name: "skip before today's slot", runs: ran(day(5, 9)), now: day(10, 8), policy: config.CatchUpSkip},
+
+		{name: "latest runs today's slot only", runs: ran(day(5, 9)), now: day(10, 12), policy: config.CatchUpLatest, want: []run{{day(10, 9), false}}},
+		{name: "latest backfills the last missed slot", runs: ran(day(5, 9)), now: day(10, 8), policy: config.CatchUpLatest, want: []run{{day(9, 9), true}}},
+		{name: "latest with nothing missed", runs: ran(day(9, 9)), now: day(10, 8), policy: config.CatchUpLatest},
+		{
+			name: "latest finds a sparse slot far back", cron: "0 9 1 * *",
+			runs: ran(time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)), now: day(10, 8), policy: config.CatchUpLatest,
+			want: []run{{day(1, 9), true}},
+		},
+
+		{
+			name: "all runs every missed slot, then today's", runs: ran(day(7, 9)), now: day(10, 12), policy: config.CatchUpAll, max: 5,
+			want: []run{{day(8, 9), true}, {day(9, 9), true}, {day(10, 9), false}},
+		},
+		{
+			name: "all caps the backlog and holds today's slot", runs: ran(day(5, 9)), now: day(10, 12), policy: config.CatchUpAll, max: 2,
+			want: []run{{day(6, 9), true}, {day(7, 9), true}},
+		},
+		{
+			name: "all with exactly max missed", runs: ran(day(7, 9)), now: day(10, 12), policy: config.CatchUpAll, max: 2,
+			want: []run{{day(8, 9), true}, {day(9, 9), true}, {day(10, 9), false}},
+		},
+		{
+			name: "all after a long outage on a frequent schedule", cron: "* * * * *",
+			runs: ran(time.Date(2021, 3, 10, 0, 0, 0, 0, time.UTC)), now: day(10, 12), policy: config.CatchUpAll, max: 2,
+			want: []run{{time.Date(2021, 3, 10, 0, 1, 0, 0, time.UTC), true}, {time.Date(2021, 3, 10, 0, 2, 0, 0, time.UTC), true}},
+		},
+		{
+			name: "latest after a long outage on a frequent schedule", cron: "* * * * *",
+			runs: ran(time.Date(2021, 3, 10, 0, 0, 0, 0, time.UTC)), now: day(10, 12), policy: config.CatchUpLatest,
+			want: []run{{day(10, 12), false}},
+		},
+
+		{
+			name: "slots before resuming were paused", runs: ran(day(5, 9)), resumed: ptr(day(8, 12)), now: day(10, 12), policy: config.CatchUpAll, max: 5,
+			want: []run{{day(9, 9), true}, {day(10, 9), false}},
+		},
+		{
+			name: "manual runs cover nothing",
+			runs: append(ran(day(8, 9)), model.ObjectiveV1JsonRunsElem{Timestamp: day(9, 20), Manual: true}),
+			now:  day(10, 12), policy: config.CatchUpAll, max: 5,
+			want: []run{{day(9, 9), true}, {day(10, 9), false}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cron := tt.cron
+			if cron == "" {
+				cron = "0 9 * * *"
+			}
+			obj := model.ObjectiveV1Json{RunSchedule: model.RunScheduleCron, ScheduleCron: cron, Runs: tt.runs, ResumedAt: tt.resumed}
+			sched, err := schedule.For(obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []run
+			for _, r := range planRuns(sched, obj, tt.now, tt.policy, tt.max) {
+				if !r.Timestamp.Equal(tt.now) {
+					t.Errorf("run %v has timestamp %v, want %v", r.ScheduledFor, r.Timestamp, tt.now)
+				}
+				got = append(got, run{r.ScheduledFor, r.Backfill})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("planRuns = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
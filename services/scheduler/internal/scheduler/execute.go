@@ -6,130 +6,211 @@ import (
     "encoding/hex"
     "encoding/json"
     "fmt"
-    "log"
+    "log/slog"
     "time"
 
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/trace"
+
     "llm-your-business/schemas/events"
     model "llm-your-business/services/go/models"
+    "llm-your-business/services/scheduler/internal/budget"
+    "llm-your-business/services/scheduler/internal/config"
+    "llm-your-business/services/scheduler/internal/db"
+    "llm-your-business/services/scheduler/internal/decode"
+    "llm-your-business/services/scheduler/internal/logging"
+    "llm-your-business/services/scheduler/internal/metrics"
+    "llm-your-business/services/scheduler/internal/topics"
 )
 
-// executeObjective gathers questions for the objective, builds a manifest event,
-// and publishes it to Kafka. Returns the manifest_id on success.
-func (s *Service) executeObjective(ctx context.Context, id string, obj model.ObjectiveV1Json) (string, error) {
+// executeObjective gathers questions for the objective, builds the manifest
+// and question events, and writes them to the outbox together with the run
+// record. The outbox relay publishes them to Kafka. run carries the run's
+// timestamp and schedule slot; its manifest_id is filled in here. Returns the
+// manifest_id on success, or "" when the objective has no questions or the
+// run was skipped over budget. Runs refused or deferred over budget return a
+// *budget.Exceeded.
+func (s *Service) executeObjective(ctx context.Context, id string, obj model.ObjectiveV1Json, run model.ObjectiveV1JsonRunsElem) (manifestID string, err error) {
+    ctx = logging.With(ctx, "objective_id", id)
+    // The outbox entries carry this span's context, so the run's Kafka
+    // messages and everything downstream of them share its trace.
+    ctx, span := tracer.Start(ctx, "execute objective", trace.WithAttributes(
+        attribute.String("objective.id", id),
+        attribute.String("run.trigger", runTrigger(run)),
+        attribute.String("run.scheduled_for", run.ScheduledFor.UTC().Format(time.RFC3339)),
+    ))
+    defer func() {
+        span.SetAttributes(attribute.String("manifest.id", manifestID))
+        endSpan(span, err)
+    }()
+
+    if s.Budgets != nil {
+        exceeded, err := s.Budgets.Check(ctx, id, obj, time.Now())
+        if err != nil {
+            return "", fmt.Errorf("check budget: %w", err)
+        }
+        if exceeded != nil {
+            return "", s.overBudget(ctx, id, run, exceeded)
+        }
+    }
+
     // Load questions from DB
     questions, err := s.DB.FindQuestionsByObjective(ctx, id)
     if err != nil {
         return "", fmt.Errorf("load questions: %w", err)
     }
     if len(questions) == 0 {
-        log.Printf("scheduler: no questions found for objective id=%s; skipping manifest", id)
+        slog.InfoContext(ctx, "scheduler: no questions found for objective; skipping manifest")
         return "", nil
     }
 
+    qtype, err := deriveQuestionType(obj)
+    if err != nil {
+        return "", fmt.Errorf("derive question type: %w", err)
+    }
+    exps, err := expandQuestions(id, obj, questions, s.cfg.MaxFanout)
+    if err != nil {
+        return "", err
+    }
+
     // Build manifest event (questions array contains only question_id)
-    manifestID := uuidV4()
+    manifestID = uuidV4()
     executionID := uuidV4()
-    type manifestMeta struct {
-        SchemaVersion int    `json:"schema_version"`
-        CreatedAt     int    `json:"created_at"`
-        Producer      string `json:"producer"`
-        ManifestId    string `json:"manifest_id"`
-        ExecutionId   string `json:"execution_id"`
-        ObjectiveId   string `json:"objective_id"`
-    }
-    type manifestQuestion struct { QuestionId string `json:"question_id"` }
-    type manifestData struct { Questions []manifestQuestion `json:"questions"` }
-    type manifestEvent struct { Meta manifestMeta `json:"meta"`; Data manifestData `json:"data"` }
-
-    mevt := manifestEvent{
-        Meta: manifestMeta{
-            SchemaVersion: 1,
+    mevt := events.ObjectiveManifestV1Json{
+        Meta: events.ObjectiveManifestV1JsonMeta{
+            SchemaVersion: decode.Manifest.Current(),
             CreatedAt:     int(time.Now().UTC().UnixMilli()),
             Producer:      "scheduler",
             ManifestId:    manifestID,
             ExecutionId:   executionID,
             ObjectiveId:   id,
         },
-        Data: manifestData{Questions: make([]manifestQuestion, 0, len(questions))},
+        Data: events.ObjectiveManifestV1JsonData{Questions: make([]events.ObjectiveManifestV1JsonDataQuestionsElem, 0, len(exps))},
+    }
+    for _, e := range exps {
+        mevt.Data.Questions = append(mevt.Data.Questions, events.ObjectiveManifestV1JsonDataQuestionsElem{QuestionId: e.id})
     }
-    for _, q := range questions {
-        mevt.Data.Questions = append(mevt.Data.Questions, manifestQuestion{QuestionId: q.QuestionId})
+    payload, err := s.marshalEvent(topics.TopicObjectiveManifest, mevt)
+    if err != nil {
+        return "", err
     }
-    payload, err := json.Marshal(mevt)
-    if err != nil { return "", fmt.Errorf("marshal manifest: %w", err) }
-    if err := s.Producer.PublishObjectiveManifestRaw(ctx, executionID, payload); err != nil { return "", fmt.Errorf("publish manifest: %w", err) }
 
-    // After manifest, emit one ObjectiveExecutionQuestion per question
+    // The manifest goes first so consumers know the expected question count
+    // before any question arrives; the relay preserves this order.
+    outbox := make([]db.OutboxMessage, 0, len(exps)+1)
+    outbox = append(outbox, db.OutboxMessage{Topic: topics.TopicObjectiveManifest, Key: executionID, Payload: string(payload)})
+
+    // After manifest, emit one ObjectiveExecutionQuestion per expanded question
     nowMillis := int(time.Now().UTC().UnixMilli())
-    for _, q := range questions {
-        mdl, err := deriveModel(obj)
-        if err != nil {
-            return "", fmt.Errorf("derive model: %w", err)
-        }
-        qtype, err := deriveQuestionType(obj)
-        if err != nil {
-            return "", fmt.Errorf("derive question type: %w", err)
-        }
-        loc := deriveLocation(obj, q)
+    records := make([]db.QuestionExpansion, 0, len(exps))
+    for _, e := range exps {
         qe := events.ObjectiveExecutionQuestionV1Json{
             Meta: events.ObjectiveExecutionQuestionV1JsonMeta{
-                SchemaVersion: 1,
+                SchemaVersion: decode.Question.Current(),
                 CreatedAt:     nowMillis,
                 Producer:      "scheduler",
                 RunAttempt:    1,
                 ManifestId:    manifestID,
                 ExecutionId:   executionID,
                 ObjectiveId:   id,
-                QuestionId:    q.QuestionId,
+                QuestionId:    e.id,
                 QuestionType:  qtype,
-                Persona:       q.Persona.Type,
-                Language:      events.Language(q.Language),
-                Location:      loc,
-                Model:         mdl,
+                Persona:       e.persona,
+                Language:      e.language,
+                Location:      e.location,
+                Model:         e.model,
             },
             Data: events.ObjectiveExecutionQuestionV1JsonData{
-                Prompt: q.QuestionText,
+                Prompt: e.question.QuestionText,
             },
         }
-        if err := s.Producer.PublishObjectiveExecutionQuestion(ctx, qe); err != nil {
-            log.Printf("scheduler: failed to publish question event: objective_id=%s question_id=%s err=%v", id, q.QuestionId, err)
+        qpayload, err := s.marshalEvent(topics.TopicObjectiveExecutionQuestion, qe)
+        if err != nil {
+            return "", fmt.Errorf("question %s: %w", e.id, err)
         }
+        outbox = append(outbox, db.OutboxMessage{Topic: topics.TopicObjectiveExecutionQuestion, Key: executionID, Payload: string(qpayload)})
+        records = append(records, db.QuestionExpansion{
+            QuestionId:       e.id,
+            SourceQuestionId: e.question.QuestionId,
+            ObjectiveId:      id,
+            Model:            string(e.model),
+            Persona:          e.persona,
+            Language:         string(e.language),
+            Location:         e.location,
+        })
+    }
+
+    // Expanded ids are deterministic, so recording the mapping ahead of the
+    // run is harmless if the enqueue below fails.
+    if err := s.DB.RecordQuestionExpansions(ctx, records); err != nil {
+        return "", err
+    }
+
+    // Manifest, questions and run record are committed atomically.
+    run.ManifestId = manifestID
+    if err := s.DB.EnqueueObjectiveRun(ctx, id, run, outbox); err != nil {
+        return "", fmt.Errorf("enqueue run: %w", err)
     }
+    s.wakeRelay()
+    metrics.ObjectivesExecuted.WithLabelValues(runTrigger(run)).Inc()
 
-    log.Printf("scheduler: published manifest: objective_id=%s manifest_id=%s execution_id=%s questions=%d", id, manifestID, executionID, len(questions))
+    slog.InfoContext(ctx, "scheduler: enqueued manifest", "manifest_id", manifestID, "execution_id", executionID,
+        "questions", len(questions), "expanded", len(exps), "scheduled_for", run.ScheduledFor.UTC(), "trigger", runTrigger(run))
     return manifestID, nil
 }
 
-// deriveModel picks a model to use for a question based on the objective's
-// llm_models config. Falls back to CHAT_GPT5 if not present.
-func deriveModel(obj model.ObjectiveV1Json) (events.Model, error) {
-    // Prefer typed access if available
-    if len(obj.LlmModels) > 0 {
-        s := string(obj.LlmModels[0])
-        switch s {
-        case string(events.ModelCHATGPT5), string(events.ModelCLAUDE35), string(events.ModelGEMINI2), string(events.ModelLLAMA4):
-            return events.Model(s), nil
-        default:
-            return "", fmt.Errorf("unsupported model: %s", s)
-        }
+// overBudget handles a run whose objective or partner budget is exhausted.
+// Manual runs are refused. Scheduled runs are deferred, leaving the slot due
+// and recording the reason in the objective's budget_hold, or recorded as
+// skipped with the reason, per BUDGET_EXHAUSTED_ACTION. Returns ex unless
+// the run was skipped.
+func (s *Service) overBudget(ctx context.Context, id string, run model.ObjectiveV1JsonRunsElem, ex *budget.Exceeded) error {
+    action := s.cfg.BudgetAction
+    if run.Manual {
+        action = "refuse"
     }
-    // Fallback: attempt dynamic extraction
-    var m map[string]interface{}
-    if b, err := json.Marshal(obj); err == nil {
-        if json.Unmarshal(b, &m) == nil {
-            if arr, ok := m["llm_models"].([]interface{}); ok && len(arr) > 0 {
-                if s, ok := arr[0].(string); ok && s != "" {
-                    switch s {
-                    case string(events.ModelCHATGPT5), string(events.ModelCLAUDE35), string(events.ModelGEMINI2), string(events.ModelLLAMA4):
-                        return events.Model(s), nil
-                    default:
-                        return "", fmt.Errorf("unsupported model: %s", s)
-                    }
-                }
-            }
+    metrics.RunsOverBudget.WithLabelValues(ex.Scope, ex.Period, action).Inc()
+    slog.WarnContext(ctx, "scheduler: budget exhausted", "action", action, "scheduled_for", run.ScheduledFor.UTC(), "err", ex)
+
+    switch action {
+    case config.BudgetSkip:
+        run.Skipped = true
+        run.SkipReason = ex.Reason()
+        if err := s.DB.RecordObjectiveRun(ctx, id, run); err != nil {
+            return fmt.Errorf("record skipped run: %w", err)
+        }
+        return nil
+    case config.BudgetDefer:
+        if err := s.DB.SetBudgetHold(ctx, id, ex.Reason(), time.Now()); err != nil {
+            return fmt.Errorf("record budget hold: %w", err)
         }
     }
-    return "", fmt.Errorf("no llm_models configured")
+    return ex
+}
+
+// marshalEvent encodes evt for topic and checks it against the topic's event
+// schema, so an invalid event fails the run here rather than being enqueued
+// and refused by the producer later.
+func (s *Service) marshalEvent(topic string, evt any) ([]byte, error) {
+    payload, err := json.Marshal(evt)
+    if err != nil {
+        return nil, fmt.Errorf("marshal %s: %w", topic, err)
+    }
+    if err := s.Producer.Validate(topic, payload); err != nil {
+        return nil, err
+    }
+    return payload, nil
+}
+
+// runTrigger labels what caused run: an operator, a missed slot or the schedule.
+func runTrigger(run model.ObjectiveV1JsonRunsElem) string {
+    switch {
+    case run.Manual:
+        return "manual"
+    case run.Backfill:
+        return "backfill"
+    }
+    return "scheduled"
 }
 
 func deriveQuestionType(obj model.ObjectiveV1Json) (events.QuestionType, error) {
@@ -163,46 +244,6 @@ func deriveQuestionType(obj model.ObjectiveV1Json) (events.QuestionType, error)
     return "", fmt.Errorf("objective_type not set")
 }
 
-// deriveLocation attempts to find a location from the objective's targets
-// that matches the question's persona and language. Returns empty string if none.
-func deriveLocation(obj model.ObjectiveV1Json, q model.QuestionV1Json) string {
-    // With the new model, targets is a single object with slice fields.
-    // We match if the question persona and language are present; then pick the first location.
-    if len(obj.Targets.Location) == 0 && len(obj.Targets.Persona) == 0 && len(obj.Targets.Language) == 0 {
-        return ""
-    }
-    qLang := string(q.Language)
-    qPersona := q.Persona.Type
-
-    containsStr := func(list []string, s string) bool {
-        for _, v := range list {
-            if v == s { return true }
-        }
-        return false
-    }
-    containsLang := func(list []model.Language, s string) bool {
-        for _, v := range list {
-            if string(v) == s { return true }
-        }
-        return false
-    }
-
-    // Prefer both persona and language present
-    if (len(obj.Targets.Persona) == 0 || containsStr(obj.Targets.Persona, qPersona)) &&
-       (len(obj.Targets.Language) == 0 || containsLang(obj.Targets.Language, qLang)) {
-        if len(obj.Targets.Location) > 0 { return obj.Targets.Location[0] }
-    }
-
-    // Fallback: language only
-    if len(obj.Targets.Language) == 0 || containsLang(obj.Targets.Language, qLang) {
-        if len(obj.Targets.Location) > 0 { return obj.Targets.Location[0] }
-    }
-
-    // Last resort: any configured location
-    if len(obj.Targets.Location) > 0 { return obj.Targets.Location[0] }
-    return ""
-}
-
 // uuidV4 generates a random RFC4122 version 4 UUID as a string.
 func uuidV4() string {
     b := make([]byte, 16)
@@ -213,7 +254,11 @@ func uuidV4() string {
     // Set version (4) and variant (RFC4122)
     b[6] = (b[6] & 0x0f) | 0x40
     b[8] = (b[8] & 0x3f) | 0x80
-    // encode as 8-4-4-4-12
+    return formatUUID(b)
+}
+
+// formatUUID encodes 16 bytes as 8-4-4-4-12 hex.
+func formatUUID(b []byte) string {
     dst := make([]byte, 36)
     hex.Encode(dst[0:8], b[0:4])
     dst[8] = '-'
@@ -0,0 +1,134 @@
+package scheduler
+
+import (
+	"crypto/sha1"
+	"errors"
+	"fmt"
+
+	"llm-your-business/schemas/events"
+	model "llm-your-business/services/go/models"
+)
+
+// expansion is one question asked with one model, as one persona, in one
+// language and location.
+type expansion struct {
+	id       string // expanded question_id
+	question model.QuestionV1Json
+	model    events.Model
+	persona  string
+	language events.Language
+	location string
+}
+
+// expandQuestions builds the full cross product of the objective's questions
+// with its llm_models and its target personas, languages and locations. A
+// target list that is empty falls back to the question's own persona or
+// language, and to an empty location. The result is refused when it exceeds
+// max questions (0 means no cap).
+func expandQuestions(objectiveID string, obj model.ObjectiveV1Json, questions []model.QuestionV1Json, max int) ([]expansion, error) {
+	models, err := deriveModels(obj)
+	if err != nil {
+		return nil, fmt.Errorf("derive models: %w", err)
+	}
+	personas := dedupe(obj.Targets.Persona)
+	languages := targetLanguages(obj)
+	locations := dedupe(obj.Targets.Location)
+	if len(locations) == 0 {
+		locations = []string{""}
+	}
+
+	var out []expansion
+	for _, q := range questions {
+		qpersonas := personas
+		if len(qpersonas) == 0 {
+			qpersonas = []string{q.Persona.Name}
+		}
+		qlanguages := languages
+		if len(qlanguages) == 0 {
+			if q.Language == "" {
+				return nil, fmt.Errorf("question %s has no language and the objective targets none", q.QuestionId)
+			}
+			qlanguages = []events.Language{events.Language(q.Language)}
+		}
+		for _, m := range models {
+			for _, persona := range qpersonas {
+				for _, lang := range qlanguages {
+					for _, loc := range locations {
+						if max > 0 && len(out) == max {
+							return nil, fmt.Errorf("objective %s expands to more than %d questions (%d questions x %d models x %d personas x %d languages x %d locations); raise FANOUT_MAX_QUESTIONS or trim its targets",
+								objectiveID, max, len(questions), len(models), len(qpersonas), len(qlanguages), len(locations))
+						}
+						out = append(out, expansion{
+							id:       expandedQuestionID(q.QuestionId, m, persona, lang, loc),
+							question: q,
+							model:    m,
+							persona:  persona,
+							language: lang,
+							location: loc,
+						})
+					}
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+// deriveModels returns the objective's llm_models, deduplicated and in order.
+func deriveModels(obj model.ObjectiveV1Json) ([]events.Model, error) {
+	if len(obj.LlmModels) == 0 {
+		return nil, errors.New("no llm_models configured")
+	}
+	seen := make(map[string]bool, len(obj.LlmModels))
+	out := make([]events.Model, 0, len(obj.LlmModels))
+	for _, s := range obj.LlmModels {
+		switch s {
+		case string(events.ModelCHATGPT5), string(events.ModelCLAUDE35), string(events.ModelGEMINI2), string(events.ModelLLAMA4):
+		default:
+			return nil, fmt.Errorf("unsupported model: %s", s)
+		}
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, events.Model(s))
+		}
+	}
+	return out, nil
+}
+
+// targetLanguages returns the objective's target languages, deduplicated.
+func targetLanguages(obj model.ObjectiveV1Json) []events.Language {
+	seen := make(map[model.Language]bool, len(obj.Targets.Language))
+	var out []events.Language
+	for _, l := range obj.Targets.Language {
+		if l != "" && !seen[l] {
+			seen[l] = true
+			out = append(out, events.Language(l))
+		}
+	}
+	return out
+}
+
+// dedupe returns the non-empty values of in, deduplicated and in order.
+func dedupe(in []string) []string {
+	seen := make(map[string]bool, len(in))
+	var out []string
+	for _, v := range in {
+		if v != "" && !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// expandedQuestionID derives a stable, UUID-shaped question_id for one
+// combination (a name-based version 5 layout over SHA-1), so the same
+// question, model, persona, language and location map to the same id on
+// every run.
+func expandedQuestionID(questionID string, m events.Model, persona string, lang events.Language, location string) string {
+	sum := sha1.Sum([]byte(questionID + "\x00" + string(m) + "\x00" + persona + "\x00" + string(lang) + "\x00" + location))
+	b := sum[:16]
+	b[6] = (b[6] & 0x0f) | 0x50
+	b[8] = (b[8] & 0x3f) | 0x80
+	return formatUUID(b)
+}
@@ -0,0 +1,126 @@
+package scheduler
+
+import (
+	"strings"
+	"testing"
+
+	model "llm-your-business/services/go/models"
+)
+
+func TestExpandQuestions(t *testing.T) {
+	questions := []model.QuestionV1Json{
+		{QuestionId: "q1", Language: "en"},
+		{QuestionId: "q2", Language: "de"},
+	}
+	tests := []struct {
+		name    string
+		targets model.ObjectiveTargets
+		models  []string
+		max     int
+		want    int
+		wantErr string
+	}{
+		{name: "no targets", models: []string{"CHAT_GPT5"}, want: 2},
+		{name: "duplicate models", models: []string{"CHAT_GPT5", "CHAT_GPT5", "GEMINI_2"}, want: 4},
+		{
+			name:    "full cross product",
+			targets: model.ObjectiveTargets{Persona: []string{"buyer", "expert"}, Language: []model.Language{"en", "de", "fr"}, Location: []string{"DE", "US"}},
+			models:  []string{"CHAT_GPT5", "GEMINI_2"},
+			want:    2 * 2 * 2 * 3 * 2,
+		},
+		{
+			name:    "empty and duplicate targets",
+			targets: model.ObjectiveTargets{Persona: []string{"", "buyer", "buyer"}, Language: []model.Language{"en", "en"}, Location: []string{"DE", ""}},
+			models:  []string{"CHAT_GPT5"},
+			want:    2,
+		},
+		{name: "at the cap", models: []string{"CHAT_GPT5", "GEMINI_2"}, max: 4, want: 4},
+		{name: "over the cap", models: []string{"CHAT_GPT5", "GEMINI_2"}, max: 3, wantErr: "more than 3 questions"},
+		{name: "unsupported model", models: []string{"GPT_2"}, wantErr: "unsupported model"},
+		{name: "no models", wantErr: "no llm_models"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := model.ObjectiveV1Json{LlmModels: tt.models, Targets: tt.targets}
+			got, err := expandQuestions("obj", obj, questions, tt.max)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tt.want {
+				t.Fatalf("got %d expansions, want %d", len(got), tt.want)
+			}
+			ids := make(map[string]bool, len(got))
+			for _, e := range got {
+				if ids[e.id] {
+					t.Fatalf("duplicate id %s for %+v", e.id, e)
+				}
+				ids[e.id] = true
+			}
+		})
+	}
+}
+
+func TestExpandQuestionsLanguage(t *testing.T) {
+	obj := model.ObjectiveV1Json{LlmModels: []string{"CHAT_GPT5"}}
+	got, err := expandQuestions("obj", obj, []model.QuestionV1Json{{QuestionId: "q1", Language: "de"}}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].language != "de" {
+		t.Fatalf("without targets the question's language is used, got %+v", got)
+	}
+
+	obj.Targets.Language = []model.Language{"en", "fr"}
+	got, err = expandQuestions("obj", obj, []model.QuestionV1Json{{QuestionId: "q1", Language: "de"}}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].language != "en" || got[1].language != "fr" {
+		t.Fatalf("targets replace the question's language, got %+v", got)
+	}
+
+	obj.Targets.Language = nil
+	if _, err := expandQuestions("obj", obj, []model.QuestionV1Json{{QuestionId: "q1"}}, 0); err == nil {
+		t.Fatal("a question without a language and no target languages must be refused")
+	}
+}
+
+func TestExpandQuestionsPersona(t *testing.T) {
+	q := model.QuestionV1Json{QuestionId: "q1", Language: "en", Persona: model.Persona{Name: "Budget Buyer"}}
+	obj := model.ObjectiveV1Json{LlmModels: []string{"CHAT_GPT5"}}
+	got, err := expandQuestions("obj", obj, []model.QuestionV1Json{q}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].persona != "Budget Buyer" {
+		t.Fatalf("without targets the question's persona is used, got %+v", got)
+	}
+
+	obj.Targets.Persona = []string{"expert"}
+	got, err = expandQuestions("obj", obj, []model.QuestionV1Json{q}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].persona != "expert" {
+		t.Fatalf("targets replace the question's persona, got %+v", got)
+	}
+}
+
+func TestExpandedQuestionIDStable(t *testing.T) {
+	a := expandedQuestionID("q1", "CHAT_GPT5", "buyer", "en", "DE")
+	if b := expandedQuestionID("q1", "CHAT_GPT5", "buyer", "en", "DE"); a != b {
+		t.Fatalf("same combination gave %s and %s", a, b)
+	}
+	if b := expandedQuestionID("q1", "CHAT_GPT5", "expert", "en", "DE"); a == b {
+		t.Fatal("persona does not change the id")
+	}
+	if len(a) != 36 || a[14] != '5' {
+		t.Fatalf("id %s is not a version 5 UUID", a)
+	}
+}
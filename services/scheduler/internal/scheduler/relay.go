@@ -0,0 +1,162 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+
+	"llm-your-business/schemas/validate"
+	"llm-your-business/services/scheduler/internal/db"
+	"llm-your-business/services/scheduler/internal/metrics"
+	"llm-your-business/services/scheduler/internal/topics"
+)
+
+// outboxBatch caps how many pending messages one relay pass publishes.
+const outboxBatch = 100
+
+// RunOutboxRelay publishes pending outbox messages to Kafka and marks them
+// sent. It polls every OUTBOX_POLL_INTERVAL and right after executeObjective
+// enqueues a run, and blocks until ctx is canceled. Only the leader relays.
+func (s *Service) RunOutboxRelay(ctx context.Context) error {
+	if s.DB == nil {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	ticker := time.NewTicker(s.cfg.OutboxPollInterval)
+	defer ticker.Stop()
+	for {
+		if s.isLeader() {
+			// A pass under way when ctx is canceled finishes.
+			work, done := s.inflight.Start(ctx)
+			if err := s.relayOnce(work); err != nil && work.Err() == nil {
+				slog.ErrorContext(ctx, "scheduler: outbox relay error", "err", err)
+			}
+			done()
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		case <-s.relayWake:
+		}
+	}
+}
+
+// outboxMaxBackoff caps the wait between publish attempts of one entry.
+const outboxMaxBackoff = 5 * time.Minute
+
+// relayOnce publishes pending messages in order. Messages share their key
+// with the rest of their execution. When one fails it is retried on a later
+// pass, and the messages behind it with the same key are held back until it
+// is sent, so a manifest is never overtaken by its own questions. Other
+// executions' messages go ahead. It returns the first publish error.
+func (s *Service) relayOnce(ctx context.Context) error {
+	held := make(map[string]bool) // keys waiting for an earlier message
+	var firstErr error
+	for {
+		msgs, err := s.DB.PendingOutbox(ctx, outboxBatch, slices.Collect(maps.Keys(held)))
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, m := range msgs {
+			if held[m.Key] {
+				continue
+			}
+			if m.NextAttemptAt != nil && m.NextAttemptAt.After(now) {
+				held[m.Key] = true
+				continue
+			}
+			// Publish under the span that enqueued the message.
+			pctx := otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(m.TraceContext))
+			if err := s.Producer.Publish(pctx, m.Topic, []byte(m.Key), []byte(m.Payload)); err != nil {
+				// Republishing an invalid payload cannot succeed; set it
+				// aside instead of stalling the relay on it.
+				var verr *validate.Error
+				if errors.As(err, &verr) {
+					slog.ErrorContext(ctx, "scheduler: outbox message fails its schema; not publishing", "outbox_id", m.ID.Hex(), "topic", m.Topic, "problems", verr.Problems)
+					if perr := s.parkOutbox(ctx, m, db.OutboxInvalid, err); perr != nil {
+						return perr
+					}
+					continue
+				}
+				// So are messages Kafka keeps refusing, such as one over the
+				// broker's size limit.
+				if m.Attempts+1 >= s.cfg.OutboxMaxAttempts {
+					slog.ErrorContext(ctx, "scheduler: outbox message keeps failing; giving up", "outbox_id", m.ID.Hex(), "topic", m.Topic, "attempts", m.Attempts+1, "err", err)
+					if perr := s.parkOutbox(ctx, m, db.OutboxFailed, err); perr != nil {
+						return perr
+					}
+					continue
+				}
+				retryAt := now.Add(outboxBackoff(s.cfg.OutboxPollInterval, m.Attempts+1))
+				if merr := s.DB.MarkOutboxFailed(ctx, m.ID, err, retryAt); merr != nil {
+					slog.ErrorContext(ctx, "scheduler: outbox mark failed error", "outbox_id", m.ID.Hex(), "topic", m.Topic, "err", merr)
+				}
+				held[m.Key] = true
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			// A crash between publish and mark re-publishes the message on
+			// restart; consumers dedupe on (execution_id, question_id, run_attempt).
+			if err := s.DB.MarkOutboxSent(ctx, m.ID); err != nil {
+				return err
+			}
+			switch m.Topic {
+			case topics.TopicObjectiveManifest:
+				metrics.ManifestsPublished.Inc()
+			case topics.TopicObjectiveExecutionQuestion:
+				metrics.QuestionsPublished.Inc()
+			}
+		}
+		// Held keys are left out of the next page, so each page makes progress.
+		if len(msgs) < outboxBatch {
+			return firstErr
+		}
+	}
+}
+
+// parkOutbox sets m aside with status. A parked manifest takes the rest of
+// its execution with it: its questions must not be published without it.
+func (s *Service) parkOutbox(ctx context.Context, m db.OutboxMessage, status string, cause error) error {
+	if err := s.DB.ParkOutbox(ctx, m.ID, status, cause); err != nil {
+		return err
+	}
+	if m.Topic != topics.TopicObjectiveManifest {
+		return nil
+	}
+	n, err := s.DB.ParkOutboxKey(ctx, m.Key, status, fmt.Errorf("manifest %s is %s: %w", m.ID.Hex(), status, cause))
+	if err != nil {
+		return err
+	}
+	slog.WarnContext(ctx, "scheduler: outbox messages of the execution set aside with its manifest", "execution_id", m.Key, "status", status, "count", n)
+	return nil
+}
+
+// outboxBackoff returns the wait after a message's attempt-th failed
+// publish: the poll interval, doubling per attempt up to outboxMaxBackoff.
+func outboxBackoff(poll time.Duration, attempt int) time.Duration {
+	d := poll
+	for i := 1; i < attempt && d < outboxMaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, outboxMaxBackoff)
+}
+
+// wakeRelay triggers a relay pass without waiting for the next poll.
+func (s *Service) wakeRelay() {
+	select {
+	case s.relayWake <- struct{}{}:
+	default:
+	}
+}
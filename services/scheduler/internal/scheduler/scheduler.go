@@ -1,131 +1,184 @@
 package scheduler
 
 import (
-    "context"
-    "log"
-    "time"
-
-    model "llm-your-business/services/go/models"
-    "llm-your-business/services/scheduler/internal/config"
-    "llm-your-business/services/scheduler/internal/db"
-    "llm-your-business/services/scheduler/internal/kafka"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"llm-your-business/services/scheduler/internal/budget"
+	"llm-your-business/services/scheduler/internal/config"
+	"llm-your-business/services/scheduler/internal/db"
+	"llm-your-business/services/scheduler/internal/drain"
+	"llm-your-business/services/scheduler/internal/kafka"
+	"llm-your-business/services/scheduler/internal/leader"
+	"llm-your-business/services/scheduler/internal/metrics"
+	"llm-your-business/services/scheduler/internal/schedule"
 )
 
 // Service holds shared dependencies for scheduling operations.
 // It owns no goroutines by default; callers control lifecycle.
 type Service struct {
-    cfg      *config.Config
-    Producer *kafka.Producer
-    DB       *db.Client // may be nil when DB is disabled
+	cfg      *config.Config
+	Producer *kafka.Producer
+	DB       db.Store        // may be nil: no background scheduling
+	Leader   *leader.Elector // may be nil: this replica always leads
+	Budgets  *budget.Tracker // may be nil: budgets are not enforced
+
+	relayWake chan struct{} // nudges the outbox relay after an enqueue
+	lastLoop  atomic.Int64  // unix nanos of the scheduling loop's last progress
+	inflight  drain.Group   // running ticks, relay and watchdog passes
+}
+
+// tickInterval is how often the leader evaluates objectives.
+const tickInterval = 10 * time.Minute
+
+func New(producer *kafka.Producer, store db.Store, elector *leader.Elector, budgets *budget.Tracker, cfg *config.Config) *Service {
+	return &Service{cfg: cfg, Producer: producer, DB: store, Leader: elector, Budgets: budgets, relayWake: make(chan struct{}, 1)}
 }
 
-func New(producer *kafka.Producer, dbClient *db.Client, cfg *config.Config) *Service {
-    return &Service{cfg: cfg, Producer: producer, DB: dbClient}
+// isLeader reports whether this replica may run ticks and relay the outbox.
+func (s *Service) isLeader() bool { return s.Leader == nil || s.Leader.IsLeader() }
+
+// elected fires when this replica becomes leader; nil (never fires) without an elector.
+func (s *Service) elected() <-chan struct{} {
+	if s.Leader == nil {
+		return nil
+	}
+	return s.Leader.Elected()
 }
 
 // Start begins a periodic scan (every 10 minutes) to evaluate whether
-// active objectives should be executed today, based on their run_schedule
-// and start_date. If not executed yet today, it invokes executeObjective (TBD).
+// active objectives are due, based on their run_schedule (or schedule_cron),
+// time_zone, start_date and runs. Due slots, including ones missed while the
+// scheduler was down per CATCHUP_POLICY, are passed to executeObjective.
+// With a leader elector, only the replica holding the lease ticks; a replica
+// that becomes leader ticks immediately.
 func (s *Service) Start(ctx context.Context) error {
 	if s.DB == nil {
-		log.Printf("scheduler: DB not configured; skipping background scheduling")
+		slog.WarnContext(ctx, "scheduler: DB not configured; skipping background scheduling")
 		<-ctx.Done()
 		return ctx.Err()
 	}
 
 	// Run an immediate tick, then every 10 minutes.
-	if err := s.tick(ctx); err != nil && err != context.Canceled {
-		log.Printf("scheduler: initial tick error: %v", err)
+	s.markLoop()
+	if s.isLeader() {
+		if err := s.runTick(ctx); err != nil && err != context.Canceled {
+			slog.ErrorContext(ctx, "scheduler: initial tick error", "err", err)
+		}
+		s.markLoop()
 	}
 
-	ticker := time.NewTicker(10 * time.Minute)
+	ticker := time.NewTicker(tickInterval)
 	defer ticker.Stop()
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-ticker.C:
-			if err := s.tick(ctx); err != nil && err != context.Canceled {
-				log.Printf("scheduler: tick error: %v", err)
-			}
+		case <-s.elected():
+		}
+		if !s.isLeader() {
+			// Followers pass through too, so a new leader is not reported stale.
+			s.markLoop()
+			continue
 		}
+		if err := s.runTick(ctx); err != nil && err != context.Canceled {
+			slog.ErrorContext(ctx, "scheduler: tick error", "err", err)
+		}
+		s.markLoop()
 	}
 }
 
-func (s *Service) tick(ctx context.Context) error {
-    objs, err := s.DB.FindActiveObjectives(ctx)
-    if err != nil {
-        return err
-    }
-    now := time.Now().UTC()
-    for id, obj := range objs {
-        if !shouldRunToday(now, obj.StartDate, string(obj.RunSchedule)) {
-            continue
-        }
-        if alreadyExecutedToday(now, obj.Runs) {
-            continue
-        }
-
-        manifestID, err := s.executeObjective(ctx, id, obj)
-        if err != nil {
-            log.Printf("scheduler: execute objective error: id=%s err=%v", id, err)
-            continue
-        }
-        // After a successful execution, upsert today's run into the objective document
-        if manifestID != "" {
-            if err := s.DB.RecordObjectiveRun(ctx, id, now, manifestID); err != nil {
-                log.Printf("scheduler: record run error: id=%s err=%v", id, err)
-            }
-        }
-    }
-    return nil
+// runTick runs a tick that canceling ctx does not interrupt: an objective
+// being executed is finished, and the tick stops before the next one.
+func (s *Service) runTick(ctx context.Context) error {
+	work, done := s.inflight.Start(ctx)
+	defer done()
+	return s.tick(work, ctx.Done())
 }
 
-
-func shouldRunToday(now, start time.Time, schedule string) bool {
-    dn := dateOnly(now)
-    ds := dateOnly(start)
-    if ds.IsZero() || dn.Before(ds) {
-        return false
-    }
-    switch schedule {
-    case "weekly":
-        days := int(dn.Sub(ds).Hours() / 24)
-        return days%7 == 0
-    case "monthly":
-        // Run on the same day-of-month as start when possible.
-        // If the current month has fewer days than start's day, run on the month's last day.
-        wantDay := ds.Day()
-        last := lastDayOfMonth(dn).Day()
-        if wantDay > last {
-            return dn.Day() == last
-        }
-        return dn.Day() == wantDay
-    case "daily":
-        fallthrough
-    default:
-        return true
-    }
+// Drain waits for a running tick, relay pass or watchdog pass to finish once
+// the contexts of Start, RunOutboxRelay and RunWatchdog are canceled. When
+// ctx is done first, the work still running is canceled.
+func (s *Service) Drain(ctx context.Context) error {
+	return s.inflight.Wait(ctx)
 }
 
-func alreadyExecutedToday(now time.Time, runs []model.ObjectiveV1JsonRunsElem) bool {
-    for _, r := range runs {
-        if sameDayUTC(now, r.Timestamp) {
-            return true
-        }
-    }
-    return false
-}
+func (s *Service) tick(ctx context.Context, stop <-chan struct{}) error {
+	now := time.Now().UTC()
+	defer func() { metrics.TickDuration.Observe(time.Since(now).Seconds()) }()
+	ctx, span := tracer.Start(ctx, "scheduler tick")
+	defer span.End()
+	// Close out executions whose answers did not all arrive in time.
+	if n, err := s.DB.FailExpiredExecutions(ctx, now); err != nil {
+		slog.ErrorContext(ctx, "scheduler: fail expired executions error", "err", err)
+	} else if n > 0 {
+		slog.InfoContext(ctx, "scheduler: marked expired executions as failed", "count", n)
+	}
 
-func dateOnly(t time.Time) time.Time { return time.Date(t.UTC().Year(), t.UTC().Month(), t.UTC().Day(), 0, 0, 0, 0, time.UTC) }
-func sameDayUTC(a, b time.Time) bool {
-    u := a.UTC(); v := b.UTC()
-    return u.Year() == v.Year() && u.Month() == v.Month() && u.Day() == v.Day()
+	objs, err := s.DB.FindActiveObjectives(ctx)
+	if err != nil {
+		return err
+	}
+	for id, obj := range objs {
+		// Stop mid-tick if the lease was lost so the new leader does not race us.
+		if !s.isLeader() {
+			slog.WarnContext(ctx, "scheduler: lost leadership during tick; stopping")
+			return nil
+		}
+		select {
+		case <-stop:
+			slog.InfoContext(ctx, "scheduler: shutting down; remaining objectives wait for the next tick")
+			return nil
+		default:
+		}
+		// A long tick is still progress; liveness only fails on a stuck one.
+		s.markLoop()
+		metrics.ObjectivesEvaluated.Inc()
+		sched, err := schedule.For(obj)
+		if err != nil {
+			slog.ErrorContext(ctx, "scheduler: invalid schedule", "objective_id", id, "err", err)
+			continue
+		}
+
+		// Each run is recorded by executeObjective together with its outbox entries.
+		for _, run := range planRuns(sched, obj, now, s.cfg.CatchUpPolicy, s.cfg.CatchUpMaxRuns) {
+			if _, err := s.executeObjective(ctx, id, obj, run); err != nil {
+				// Deferred over budget: already logged; later slots wait too.
+				var exceeded *budget.Exceeded
+				if !errors.As(err, &exceeded) {
+					slog.ErrorContext(ctx, "scheduler: execute objective error", "objective_id", id, "scheduled_for", run.ScheduledFor, "err", err)
+				}
+				break
+			}
+		}
+	}
+	return nil
 }
-func lastDayOfMonth(t time.Time) time.Time {
-    u := t.UTC()
-    firstOfNext := time.Date(u.Year(), u.Month()+1, 1, 0, 0, 0, 0, time.UTC)
-    return firstOfNext.Add(-24 * time.Hour)
+
+func (s *Service) markLoop() { s.lastLoop.Store(time.Now().UnixNano()) }
+
+// CheckTicking reports an error when the scheduling loop has made no
+// progress for two tick intervals, e.g. because a tick is stuck. A pass and
+// each objective a tick evaluates count as progress, so a tick that is merely
+// long does not fail it. It passes when the DB is disabled, since the loop
+// does not run then.
+func (s *Service) CheckTicking(now time.Time) error {
+	if s.DB == nil {
+		return nil
+	}
+	last := s.lastLoop.Load()
+	if last == 0 {
+		return fmt.Errorf("scheduler loop not started")
+	}
+	if age := now.Sub(time.Unix(0, last)); age > 2*tickInterval {
+		return fmt.Errorf("scheduler loop last passed %s ago", age.Round(time.Second))
+	}
+	return nil
 }
 
 // Close performs best-effort cleanup of owned resources.
@@ -0,0 +1,201 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"llm-your-business/schemas/events"
+	model "llm-your-business/services/go/models"
+	"llm-your-business/services/scheduler/internal/config"
+	"llm-your-business/services/scheduler/internal/db/memory"
+	"llm-your-business/services/scheduler/internal/kafka"
+	kafkamemory "llm-your-business/services/scheduler/internal/kafka/memory"
+	"llm-your-business/services/scheduler/internal/topics"
+)
+
+// The test objective and its questions. IDs are UUIDs because the event
+// schemas require them.
+const (
+	objectiveID = "3f0e9d52-7c1a-4b8e-9f26-5d4c3b2a1e01"
+	questionA   = "3f0e9d52-7c1a-4b8e-9f26-5d4c3b2a1e0a"
+	questionB   = "3f0e9d52-7c1a-4b8e-9f26-5d4c3b2a1e0b"
+)
+
+// dailyObjective is an active daily objective that has never run.
+func dailyObjective() model.ObjectiveV1Json {
+	start := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -7)
+	return model.ObjectiveV1Json{
+		Title:         "Best project management tools",
+		LlmModels:     []string{string(events.ModelCHATGPT5), string(events.ModelCLAUDE35)},
+		ObjectiveType: "top_5_in_category",
+		Targets:       model.ObjectiveTargets{Language: []model.Language{"EN"}, Location: []string{"US"}},
+		IsActive:      true,
+		RunSchedule:   model.RunScheduleDaily,
+		StartDate:     start,
+		CreatedAt:     start,
+		UpdatedAt:     start,
+	}
+}
+
+// newService returns a Service on an in-memory store seeded with obj and
+// questions, and a producer on an in-memory broker.
+func newService(t *testing.T, obj model.ObjectiveV1Json, questions ...model.QuestionV1Json) (*Service, *memory.Store) {
+	t.Helper()
+	seed, err := json.Marshal(memory.Seed{
+		Objectives: map[string]model.ObjectiveV1Json{objectiveID: obj},
+		Questions:  questions,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := memory.New()
+	if err := store.Seed(bytes.NewReader(seed)); err != nil {
+		t.Fatal(err)
+	}
+	producer, err := kafka.NewProducer(kafkamemory.New(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{MaxFanout: 1000, CatchUpPolicy: config.CatchUpLatest, CatchUpMaxRuns: 10, BudgetAction: config.BudgetDefer}
+	return New(producer, store, nil, nil, cfg), store
+}
+
+func questions() []model.QuestionV1Json {
+	return []model.QuestionV1Json{
+		{QuestionId: questionA, ObjectiveId: objectiveID, QuestionText: "Which project management tools are best for small teams?", Language: "EN"},
+		{QuestionId: questionB, ObjectiveId: objectiveID, QuestionText: "Which project management tools do agencies recommend?", Language: "EN"},
+	}
+}
+
+func TestTick(t *testing.T) {
+	s, store := newService(t, dailyObjective(), questions()...)
+	ctx := context.Background()
+
+	if err := s.CheckTicking(time.Now()); err == nil {
+		t.Fatal("CheckTicking passed before the loop started")
+	}
+	if err := s.tick(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CheckTicking(time.Now()); err != nil {
+		t.Fatalf("CheckTicking after a tick: %v", err)
+	}
+
+	obj, err := store.FindObjective(ctx, objectiveID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(obj.Runs) != 1 || obj.Runs[0].ManifestId == "" || obj.Runs[0].Backfill {
+		t.Fatalf("runs after the tick: %+v", obj.Runs)
+	}
+	msgs, err := store.PendingOutbox(ctx, 100, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The manifest, then 2 questions x 2 models.
+	if len(msgs) != 1+4 {
+		t.Fatalf("outbox has %d entries, want 5", len(msgs))
+	}
+	if msgs[0].Topic != topics.TopicObjectiveManifest || !strings.Contains(msgs[0].Payload, obj.Runs[0].ManifestId) {
+		t.Fatalf("first outbox entry is %s, want the manifest", msgs[0].Topic)
+	}
+	for _, m := range msgs[1:] {
+		if m.Topic != topics.TopicObjectiveExecutionQuestion || m.Key != msgs[0].Key {
+			t.Fatalf("outbox entry %s keyed %s, want a question keyed %s", m.Topic, m.Key, msgs[0].Key)
+		}
+	}
+
+	// Today's slot has run; a second tick enqueues nothing.
+	if err := s.tick(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if msgs, _ := store.PendingOutbox(ctx, 100, nil); len(msgs) != 5 {
+		t.Fatalf("outbox has %d entries after a second tick, want 5", len(msgs))
+	}
+	if obj, _ := store.FindObjective(ctx, objectiveID); len(obj.Runs) != 1 {
+		t.Fatalf("objective ran %d times, want once", len(obj.Runs))
+	}
+}
+
+func TestTickStops(t *testing.T) {
+	s, store := newService(t, dailyObjective(), questions()...)
+	stop := make(chan struct{})
+	close(stop)
+	if err := s.tick(context.Background(), stop); err != nil {
+		t.Fatal(err)
+	}
+	if msgs, _ := store.PendingOutbox(context.Background(), 100, nil); len(msgs) != 0 {
+		t.Fatalf("a stopped tick enqueued %d entries", len(msgs))
+	}
+}
+
+func TestExecuteObjective(t *testing.T) {
+	tests := []struct {
+		name      string
+		obj       func(*model.ObjectiveV1Json)
+		questions []model.QuestionV1Json
+		wantErr   string
+		wantRun   bool
+	}{
+		{name: "enqueues the run", questions: questions(), wantRun: true},
+		{name: "no questions"},
+		{
+			name:      "over the fan-out cap",
+			obj:       func(o *model.ObjectiveV1Json) { o.Targets.Location = []string{"US", "DE", "FR", "IT"} },
+			questions: questions(),
+			wantErr:   "more than",
+		},
+		{
+			name:      "unsupported objective type",
+			obj:       func(o *model.ObjectiveV1Json) { o.ObjectiveType = "top_3_in_category" },
+			questions: questions(),
+			wantErr:   "unsupported objective_type",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := dailyObjective()
+			if tt.obj != nil {
+				tt.obj(&obj)
+			}
+			s, store := newService(t, obj, tt.questions...)
+			s.cfg.MaxFanout = 10
+			ctx := context.Background()
+			now := time.Now().UTC()
+
+			manifestID, err := s.executeObjective(ctx, objectiveID, obj, model.ObjectiveV1JsonRunsElem{Timestamp: now, ScheduledFor: now})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if (manifestID != "") != tt.wantRun {
+				t.Fatalf("manifest id %q, want a run %v", manifestID, tt.wantRun)
+			}
+
+			msgs, err := store.PendingOutbox(ctx, 100, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			stored, err := store.FindObjective(ctx, objectiveID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.wantRun {
+				if len(msgs) != 0 || len(stored.Runs) != 0 {
+					t.Fatalf("no run expected, got %d outbox entries and runs %+v", len(msgs), stored.Runs)
+				}
+				return
+			}
+			if len(msgs) != 5 || len(stored.Runs) != 1 || stored.Runs[0].ManifestId != manifestID {
+				t.Fatalf("got %d outbox entries and runs %+v", len(msgs), stored.Runs)
+			}
+		})
+	}
+}
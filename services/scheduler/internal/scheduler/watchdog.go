@@ -0,0 +1,123 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"llm-your-business/schemas/events"
+	"llm-your-business/services/scheduler/internal/db"
+	"llm-your-business/services/scheduler/internal/logging"
+	"llm-your-business/services/scheduler/internal/topics"
+)
+
+// watchdogBatch caps how many stalled questions one watchdog pass handles.
+const watchdogBatch = 100
+
+// RunWatchdog re-emits questions that have gone unanswered for
+// QUESTION_TIMEOUT with run_attempt incremented, and fails them once
+// QUESTION_MAX_ATTEMPTS attempts have timed out. It checks every
+// WATCHDOG_INTERVAL and blocks until ctx is canceled. Only the leader runs it.
+func (s *Service) RunWatchdog(ctx context.Context) error {
+	if s.DB == nil {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	ticker := time.NewTicker(s.cfg.WatchdogInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		if !s.isLeader() {
+			continue
+		}
+		// A pass under way when ctx is canceled finishes.
+		work, done := s.inflight.Start(ctx)
+		if err := s.watchdogOnce(work); err != nil && work.Err() == nil {
+			slog.ErrorContext(ctx, "scheduler: watchdog error", "err", err)
+		}
+		done()
+	}
+}
+
+func (s *Service) watchdogOnce(ctx context.Context) (err error) {
+	// Re-emitted questions are published in the trace of this pass.
+	ctx, span := tracer.Start(ctx, "watchdog pass")
+	defer func() { endSpan(span, err) }()
+
+	stalled, err := s.DB.FindStalledQuestions(ctx, time.Now().Add(-s.cfg.QuestionTimeout), watchdogBatch)
+	if err != nil {
+		return err
+	}
+	retried := 0
+	for _, q := range stalled {
+		qctx := logging.With(ctx, "manifest_id", q.ManifestId, "execution_id", q.ExecutionId, "question_id", q.QuestionId)
+		if !db.InFlight(q.ExecutionStatus) {
+			// The execution has ended (e.g. timed out); stop asking.
+			reason := fmt.Sprintf("execution %s", strings.ToLower(q.ExecutionStatus))
+			if q.ExecutionStatus == "" {
+				reason = "execution not found"
+			}
+			if err := s.DB.FailQuestion(ctx, q, reason); err != nil {
+				return err
+			}
+			continue
+		}
+		if q.RunAttempt >= s.cfg.QuestionMaxAttempts {
+			reason := fmt.Sprintf("no answer after %d attempts", q.RunAttempt)
+			if err := s.DB.FailQuestion(ctx, q, reason); err != nil {
+				return err
+			}
+			slog.WarnContext(qctx, "scheduler: question failed", "attempts", q.RunAttempt)
+			continue
+		}
+
+		msg, err := reemit(q)
+		if err != nil {
+			// A stored event that no longer decodes will not get better; give up on it.
+			slog.ErrorContext(qctx, "scheduler: cannot re-emit question", "err", err)
+			if err := s.DB.FailQuestion(ctx, q, err.Error()); err != nil {
+				return err
+			}
+			continue
+		}
+		ok, err := s.DB.RetryQuestion(ctx, q, msg)
+		if err != nil {
+			return err
+		}
+		if ok {
+			retried++
+			slog.InfoContext(qctx, "scheduler: re-emitting question", "run_attempt", q.RunAttempt+1)
+		}
+	}
+	if retried > 0 {
+		s.wakeRelay()
+	}
+	return nil
+}
+
+// reemit rebuilds the stored question event as its next attempt.
+func reemit(q db.StalledQuestion) (db.OutboxMessage, error) {
+	raw, err := json.Marshal(map[string]interface{}{"meta": q.Meta, "data": q.Data})
+	if err != nil {
+		return db.OutboxMessage{}, fmt.Errorf("encode stored question: %w", err)
+	}
+	var evt events.ObjectiveExecutionQuestionV1Json
+	if err := json.Unmarshal(raw, &evt); err != nil {
+		return db.OutboxMessage{}, fmt.Errorf("decode stored question: %w", err)
+	}
+	evt.Meta.RunAttempt = q.RunAttempt + 1
+	evt.Meta.CreatedAt = int(time.Now().UTC().UnixMilli())
+	payload, err := json.Marshal(evt)
+	if err != nil {
+		return db.OutboxMessage{}, fmt.Errorf("marshal question: %w", err)
+	}
+	return db.OutboxMessage{Topic: topics.TopicObjectiveExecutionQuestion, Key: evt.Meta.ExecutionId, Payload: string(payload)}, nil
+}
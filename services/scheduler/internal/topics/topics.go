@@ -1,8 +1,43 @@
 package topics
 
+import (
+	"strings"
+	"time"
+)
+
 const (
 	TopicObjectiveExecutionQuestion = "objective.execution.question"
 	TopicObjectiveExecutionAnswer   = "objective.execution.answer"
 	TopicObjectiveDatapoint         = "objective.datapoint"
 	TopicObjectiveManifest          = "objective.manifest"
 )
+
+// Suffixes of the per-topic retry, dead-letter and quarantine topics.
+const (
+	RetrySuffix      = ".retry"
+	DeadLetterSuffix = ".dlq"
+	QuarantineSuffix = ".quarantine"
+)
+
+// Retry returns the retry topic of a source topic for messages retried after
+// delay, e.g. "objective.manifest.retry.30s". Each backoff tier has its own
+// topic so that every message on it waits equally long, and waiting for the
+// oldest never holds back one that is already due.
+func Retry(topic string, delay time.Duration) string {
+	return topic + RetrySuffix + "." + delay.String()
+}
+
+// DeadLetter returns the dead-letter topic for a source topic.
+func DeadLetter(topic string) string { return topic + DeadLetterSuffix }
+
+// Quarantine returns the topic for messages from a source topic that do not
+// match the source topic's event schema.
+func Quarantine(topic string) string { return topic + QuarantineSuffix }
+
+// Source strips a retry suffix and reports whether the topic was a retry topic.
+func Source(topic string) (string, bool) {
+	if i := strings.LastIndex(topic, RetrySuffix+"."); i > 0 {
+		return topic[:i], true
+	}
+	return topic, false
+}
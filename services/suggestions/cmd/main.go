@@ -2,7 +2,7 @@ package main
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -11,8 +11,10 @@ import (
 
 	"llm-your-business/services/suggestions/internal/chatgpt"
 	"llm-your-business/services/suggestions/internal/config"
+	"llm-your-business/services/suggestions/internal/logging"
 	"llm-your-business/services/suggestions/internal/requests"
 	"llm-your-business/services/suggestions/internal/server"
+	"llm-your-business/services/suggestions/internal/tracing"
 )
 
 func main() {
@@ -22,8 +24,21 @@ func main() {
 
 	cfg, err := config.Load()
 	if err != nil {
-		log.Fatalf("config error: %v", err)
+		logging.Fatal("config error", "err", err)
 	}
+	logging.Setup(cfg.LogLevel)
+
+	shutdownTracing, err := tracing.Setup(ctx, "suggestions", cfg.TracesExporter)
+	if err != nil {
+		logging.Fatal("tracing init error", "err", err)
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(shutdownCtx); err != nil {
+			slog.Error("tracing shutdown error", "err", err)
+		}
+	}()
 
 	// Initialize ChatGPT connector
 	cg := &chatgpt.Client{}
@@ -34,7 +49,7 @@ func main() {
 		DefaultModel: cfg.OpenAIModel,
 		Temperature:  config.Temperature,
 	}); err != nil {
-		log.Fatalf("chatgpt init error: %v", err)
+		logging.Fatal("chatgpt init error", "err", err)
 	}
 
 	// High-level requests wrapper and HTTP server
@@ -51,9 +66,9 @@ func main() {
 	}
 
 	go func() {
-		log.Printf("suggestions service listening on :%s", cfg.Port)
+		slog.Info("suggestions service listening", "port", cfg.Port)
 		if err := httpSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("http server error: %v", err)
+			logging.Fatal("http server error", "err", err)
 		}
 	}()
 
@@ -62,9 +77,9 @@ func main() {
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	if err := httpSrv.Shutdown(shutdownCtx); err != nil {
-		log.Printf("server shutdown error: %v", err)
+		slog.Error("server shutdown error", "err", err)
 	}
 
-	_ = os.Stderr.Sync()
+	_ = os.Stdout.Sync()
 	time.Sleep(50 * time.Millisecond)
 }
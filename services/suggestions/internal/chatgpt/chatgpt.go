@@ -8,11 +8,22 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"llm-your-business/services/suggestions/internal/metrics"
 )
 
+var tracer = otel.Tracer("llm-your-business/services/suggestions/internal/chatgpt")
+
 // Client is a minimal OpenAI Responses API client.
 type Client struct {
 	http               *http.Client
@@ -119,10 +130,23 @@ func (c *Client) Chat(ctx context.Context, messages []Message, model string, tem
 }
 
 // ChatWithCache is like Chat but attaches a prompt_cache_key for caching.
-func (c *Client) ChatWithCache(ctx context.Context, messages []Message, model string, temperature float32, maxTokens int, cacheKey string) (string, Usage, error) {
+func (c *Client) ChatWithCache(ctx context.Context, messages []Message, model string, temperature float32, maxTokens int, cacheKey string) (text string, usage Usage, err error) {
 	if model == "" {
 		model = c.defaultModel
 	}
+	ctx, span := tracer.Start(ctx, "chat "+model, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("gen_ai.system", "openai"), attribute.String("gen_ai.request.model", model)))
+	defer func() {
+		span.SetAttributes(
+			attribute.Int("gen_ai.usage.input_tokens", usage.InputTokens),
+			attribute.Int("gen_ai.usage.output_tokens", usage.OutputTokens),
+		)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
 	var in []inputMessage
 	for _, m := range messages {
 		in = append(in, inputMessage{
@@ -164,26 +188,9 @@ func (c *Client) ChatWithCache(ctx context.Context, messages []Message, model st
 		reqBody.Metadata = map[string]string{}
 	}
 	reqBody.Metadata["prompt_cache_key"] = makePromptKey("system", sysText)
-	// Log the outgoing request (sanitized)
-	logReq := struct {
-		Model           string            `json:"model"`
-		Temperature     float32           `json:"temperature"`
-		MaxOutputTokens int               `json:"max_output_tokens"`
-		Metadata        map[string]string `json:"metadata,omitempty"`
-		Messages        []Message         `json:"messages"`
-	}{
-		Model:           reqBody.Model,
-		Temperature:     reqBody.Temperature,
-		MaxOutputTokens: reqBody.MaxOutputTokens,
-		Metadata:        reqBody.Metadata,
-		Messages:        make([]Message, 0, len(messages)),
-	}
-	for _, m := range messages {
-		// Truncate content to avoid oversized logs
-		logReq.Messages = append(logReq.Messages, Message{Role: m.Role, Content: truncateForLog(m.Content, 2000)})
-	}
-	if data, err := json.Marshal(logReq); err == nil {
-		log.Printf("chatgpt request: %s", data)
+	// Log the outgoing request (sanitized) at debug level; skip building it otherwise.
+	if slog.Default().Enabled(ctx, slog.LevelDebug) {
+		logRequest(ctx, reqBody, messages)
 	}
 
 	buf, err := json.Marshal(reqBody)
@@ -199,15 +206,18 @@ func (c *Client) ChatWithCache(ctx context.Context, messages []Message, model st
 	req.Header.Set("Authorization", "Bearer "+c.apiKey)
 	req.Header.Set("Content-Type", "application/json")
 
+	start := time.Now()
 	resp, err := c.http.Do(req)
 	if err != nil {
+		metrics.UpstreamDuration.WithLabelValues(model, "error").Observe(time.Since(start).Seconds())
 		return "", Usage{}, fmt.Errorf("do request: %w", err)
 	}
 	defer resp.Body.Close()
+	metrics.UpstreamDuration.WithLabelValues(model, strconv.Itoa(resp.StatusCode)).Observe(time.Since(start).Seconds())
 	if resp.StatusCode < 200 || resp.StatusCode > 299 {
 		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
 		// Log error response body
-		log.Printf("chatgpt error response (%d): %s", resp.StatusCode, truncateForLog(string(b), 4000))
+		slog.ErrorContext(ctx, "chatgpt error response", "status", resp.StatusCode, "body", truncateForLog(string(b), 4000))
 		return "", Usage{}, fmt.Errorf("openai status %d: %s", resp.StatusCode, string(b))
 	}
 	// Read full body to allow logging
@@ -215,11 +225,13 @@ func (c *Client) ChatWithCache(ctx context.Context, messages []Message, model st
 	if err != nil {
 		return "", Usage{}, fmt.Errorf("read response: %w", err)
 	}
-	log.Printf("chatgpt response raw: %s", truncateForLog(string(bodyBytes), 4000))
+	slog.DebugContext(ctx, "chatgpt response", "model", model, "body", truncateForLog(string(bodyBytes), 4000))
 	var out responsesResponse
 	if err := json.Unmarshal(bodyBytes, &out); err != nil {
 		return "", Usage{}, fmt.Errorf("decode response: %w", err)
 	}
+	metrics.UpstreamTokens.WithLabelValues(model, "input").Add(float64(out.Usage.InputTokens))
+	metrics.UpstreamTokens.WithLabelValues(model, "output").Add(float64(out.Usage.OutputTokens))
 	if s := strings.TrimSpace(out.OutputText); s != "" {
 		return s, Usage(out.Usage), nil
 	}
@@ -241,6 +253,28 @@ func (c *Client) ChatWithCache(ctx context.Context, messages []Message, model st
 	return "", Usage(out.Usage), errors.New("openai responses: no output text")
 }
 
+// logRequest logs the outgoing request with message contents truncated.
+func logRequest(ctx context.Context, reqBody responsesRequest, messages []Message) {
+	logReq := struct {
+		Model           string            `json:"model"`
+		Temperature     float32           `json:"temperature"`
+		MaxOutputTokens int               `json:"max_output_tokens"`
+		Metadata        map[string]string `json:"metadata,omitempty"`
+		Messages        []Message         `json:"messages"`
+	}{
+		Model:           reqBody.Model,
+		Temperature:     reqBody.Temperature,
+		MaxOutputTokens: reqBody.MaxOutputTokens,
+		Metadata:        reqBody.Metadata,
+		Messages:        make([]Message, 0, len(messages)),
+	}
+	for _, m := range messages {
+		// Truncate content to avoid oversized logs
+		logReq.Messages = append(logReq.Messages, Message{Role: m.Role, Content: truncateForLog(m.Content, 2000)})
+	}
+	slog.DebugContext(ctx, "chatgpt request", "request", logReq)
+}
+
 // Ask is a convenience wrapper for a single-prompt interaction.
 func (c *Client) Ask(ctx context.Context, prompt string, model string) (string, Usage, error) {
 	msgs := []Message{
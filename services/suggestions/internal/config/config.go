@@ -2,14 +2,27 @@ package config
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
+	"strings"
+
+	"llm-your-business/services/suggestions/internal/logging"
+)
+
+// Trace exporters selectable with OTEL_TRACES_EXPORTER.
+const (
+	TracesNone   = "none"   // spans are not recorded
+	TracesOTLP   = "otlp"   // OTLP over HTTP, configured by the OTEL_EXPORTER_OTLP_* variables
+	TracesStdout = "stdout" // one JSON span per line on stdout, for local use
 )
 
 type Config struct {
-	Port          string
-	OpenAIAPIKey  string
-	OpenAIModel   string
-	OpenAIBaseURL string
+	LogLevel       slog.Level
+	Port           string
+	OpenAIAPIKey   string
+	OpenAIModel    string
+	OpenAIBaseURL  string
+	TracesExporter string // one of TracesNone, TracesOTLP, TracesStdout
 }
 
 // Temperature is the constant temperature used across all ChatGPT requests.
@@ -25,10 +38,21 @@ func getenv(key, def string) string {
 
 func Load() (*Config, error) {
 	cfg := &Config{
-		Port:          getenv("PORT", "8085"),
-		OpenAIAPIKey:  os.Getenv("OPENAI_API_KEY"),
-		OpenAIModel:   getenv("OPENAI_MODEL", "gpt-4o-mini"),
-		OpenAIBaseURL: getenv("OPENAI_BASE_URL", "https://api.openai.com"),
+		Port:           getenv("PORT", "8085"),
+		OpenAIAPIKey:   os.Getenv("OPENAI_API_KEY"),
+		OpenAIModel:    getenv("OPENAI_MODEL", "gpt-4o-mini"),
+		OpenAIBaseURL:  getenv("OPENAI_BASE_URL", "https://api.openai.com"),
+		TracesExporter: strings.ToLower(getenv("OTEL_TRACES_EXPORTER", TracesNone)),
+	}
+	level, err := logging.ParseLevel(getenv("LOG_LEVEL", "info"))
+	if err != nil {
+		return nil, fmt.Errorf("LOG_LEVEL: %w", err)
+	}
+	cfg.LogLevel = level
+	switch cfg.TracesExporter {
+	case TracesNone, TracesOTLP, TracesStdout:
+	default:
+		return nil, fmt.Errorf("OTEL_TRACES_EXPORTER must be %s, %s or %s", TracesNone, TracesOTLP, TracesStdout)
 	}
 	if cfg.OpenAIAPIKey == "" {
 		// Do not hard fail to allow local testing without external calls,
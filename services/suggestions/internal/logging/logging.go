@@ -0,0 +1,84 @@
+// Package logging configures JSON logging through log/slog.
+//
+// Records use the same standard keys as the scheduler for the entities they
+// concern; here that is mostly request_id.
+// Attributes attached to a context with With are added to every record logged
+// with that context (slog.InfoContext and friends), together with the trace
+// and span IDs of the context's span.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Setup makes a JSON handler writing to stdout at level the default logger.
+// Output of the standard log package goes through it at info level.
+func Setup(level slog.Level) {
+	h := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level})
+	slog.SetDefault(slog.New(contextHandler{h}))
+}
+
+// ParseLevel accepts debug, info, warn (or warning) and error.
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q", s)
+}
+
+// Fatal logs msg at error level and exits.
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+type ctxKey struct{}
+
+// With returns a context whose log records carry args (key-value pairs or
+// slog.Attr values, as for slog.Info) in addition to those already on ctx.
+func With(ctx context.Context, args ...any) context.Context {
+	prev, _ := ctx.Value(ctxKey{}).([]slog.Attr)
+	r := slog.Record{}
+	r.Add(args...)
+	attrs := make([]slog.Attr, 0, len(prev)+r.NumAttrs())
+	attrs = append(attrs, prev...)
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	return context.WithValue(ctx, ctxKey{}, attrs)
+}
+
+// contextHandler adds the context's attributes and trace IDs to records.
+type contextHandler struct{ slog.Handler }
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs, ok := ctx.Value(ctxKey{}).([]slog.Attr); ok {
+		r.AddAttrs(attrs...)
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,30 @@
+// Package metrics defines the suggestions service's Prometheus series. They
+// register with the default registry, served on /metrics.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// HTTPRequestDuration is observed by the server's logging middleware.
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "suggestions_http_request_duration_seconds",
+		Help:    "HTTP request latency by route pattern, method and status code.",
+		Buckets: []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"path", "method", "status"})
+
+	// UpstreamDuration is the latency of calls to the OpenAI API.
+	UpstreamDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "suggestions_upstream_request_duration_seconds",
+		Help:    "Latency of OpenAI API calls by model and outcome (HTTP status, or \"error\" when no response arrived).",
+		Buckets: []float64{.25, .5, 1, 2.5, 5, 10, 20, 30, 60},
+	}, []string{"model", "status"})
+
+	// UpstreamTokens counts tokens reported in OpenAI usage.
+	UpstreamTokens = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "suggestions_upstream_tokens_total",
+		Help: "Tokens used by OpenAI API calls by model and kind (input or output).",
+	}, []string{"model", "kind"})
+)
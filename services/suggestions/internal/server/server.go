@@ -1,17 +1,31 @@
 package server
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
 	models "llm-your-business/services/go/models"
 	"llm-your-business/services/suggestions/api"
 	"llm-your-business/services/suggestions/internal/chatgpt"
+	"llm-your-business/services/suggestions/internal/logging"
+	"llm-your-business/services/suggestions/internal/metrics"
 	"llm-your-business/services/suggestions/internal/requests"
 )
 
+var tracer = otel.Tracer("llm-your-business/services/suggestions/internal/server")
+
 type Options struct {
 	ChatGPT  *chatgpt.Client
 	Requests *requests.Suggestions
@@ -33,6 +47,7 @@ func (s *Server) Router() http.Handler {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte("ok"))
 	})
+	mux.Handle("/metrics", promhttp.Handler())
 
 	mux.HandleFunc("/api/suggestions/product-description", s.postProductDescription)
 	mux.HandleFunc("/api/suggestions/categories", s.getCategories)
@@ -42,7 +57,7 @@ func (s *Server) Router() http.Handler {
 	mux.HandleFunc("/ui", s.getUIIndex)
 	mux.HandleFunc("/ui/", s.serveUI)
 
-	return cors(logging(mux))
+	return cors(instrument(mux))
 }
 
 func (s *Server) postProductDescription(w http.ResponseWriter, r *http.Request) {
@@ -154,20 +169,64 @@ func writeJSON(w http.ResponseWriter, status int, v any) {
 	_ = json.NewEncoder(w).Encode(v)
 }
 
-// logging middleware for minimal request logs
-func logging(next http.Handler) http.Handler {
+// instrument middleware for request logs, per-route latency metrics and
+// server spans. Each request is tagged with a request_id, taken from
+// X-Request-Id or generated.
+func instrument(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		next.ServeHTTP(w, r)
-		log.Printf("%s %s %s", r.Method, r.URL.Path, time.Since(start))
+		// Join the caller's trace, if any, so upstream model calls show up in it.
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method, trace.WithSpanKind(trace.SpanKindServer))
+		defer span.End()
+		requestID := r.Header.Get("X-Request-Id")
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+		w.Header().Set("X-Request-Id", requestID)
+		r = r.WithContext(logging.With(ctx, "request_id", requestID))
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		elapsed := time.Since(start)
+		// The mux sets r.Pattern; label by it rather than the raw path to
+		// keep /ui/ assets and unknown paths from exploding cardinality.
+		path := r.Pattern
+		if path == "" {
+			path = "unmatched"
+		}
+		span.SetName(r.Method + " " + path)
+		span.SetAttributes(semconv.HTTPRequestMethodKey.String(r.Method), semconv.HTTPRoute(path), semconv.HTTPResponseStatusCode(rec.status))
+		if rec.status >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(rec.status))
+		}
+		metrics.HTTPRequestDuration.WithLabelValues(path, r.Method, strconv.Itoa(rec.status)).Observe(elapsed.Seconds())
+		slog.InfoContext(r.Context(), "http request", "method", r.Method, "path", r.URL.Path, "status", rec.status, "duration", elapsed.String())
 	})
 }
 
+func newRequestID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// statusRecorder captures the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
 // cors adds permissive CORS headers so the static ui/ page can call the API.
 func cors(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-Id")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusNoContent)
@@ -0,0 +1,55 @@
+// Package tracing installs the OpenTelemetry tracer provider and the W3C
+// trace-context propagator.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+
+	"llm-your-business/services/suggestions/internal/config"
+)
+
+// Setup installs the global propagator and, unless exporter is
+// config.TracesNone, a tracer provider exporting spans for service. The
+// returned function flushes pending spans and must be called on shutdown.
+func Setup(ctx context.Context, service, exporter string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exp sdktrace.SpanExporter
+	var err error
+	switch exporter {
+	case config.TracesNone:
+		return func(context.Context) error { return nil }, nil
+	case config.TracesOTLP:
+		exp, err = otlptracehttp.New(ctx)
+	case config.TracesStdout:
+		exp, err = stdouttrace.New()
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s exporter: %w", exporter, err)
+	}
+
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the defaults.
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(service)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("trace resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp), sdktrace.WithResource(res))
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}